@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"errors"
+)
+
+// BinaryMemberType returns how a struct member is serialized by the generated
+// binary serialization code, or an error explaining why it cannot be. The c
+// and go generators both skip members that return an error so their
+// encodings always match.
+//
+// Values are written in member order without padding. Numbers use their go
+// width, strings and slices are prefixed with a uint32 length and pointers
+// with a uint8 flag that is 0 for nil.
+func (inspecter *Inspecter) BinaryMemberType(member StructMember) (*ValueType, error) {
+	vt, err := inspecter.MemberValueType(member)
+	if err != nil {
+		return nil, err
+	}
+
+	for t := vt; t != nil; t = t.Elem {
+		if t.Kind == KindMap {
+			return nil, errors.New("maps are unsupported")
+		}
+	}
+
+	return vt, nil
+}
+
+// BinaryMinSize is the fewest bytes a value can take once serialized
+func (inspecter *Inspecter) BinaryMinSize(bt *ValueType) int {
+	switch bt.Kind {
+	case KindString, KindSlice:
+		return 4
+	case KindPointer:
+		return 1
+	case KindStruct:
+		size := 0
+		for _, member := range bt.Struct.Members {
+			mt, err := inspecter.BinaryMemberType(member)
+			if err != nil {
+				continue
+			}
+			size += inspecter.BinaryMinSize(mt)
+		}
+		return size
+	}
+
+	return bt.Size
+}
@@ -2,15 +2,177 @@ package converter
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/structtag"
+	"github.com/samber/lo"
 )
 
 type CConverter struct {
+	Packed        bool   // pack every struct with __attribute__((packed))
+	Pack          int    // wrap every struct in #pragma pack(push, Pack)
+	StaticAsserts bool   // emit static_assert checks for the computed layout
+	PointerSize   int    // pointer size used when computing layouts, defaults to 8
+	BoolSize      int    // size of bool_t used when computing layouts, defaults to 1
+	Binary        bool   // declare the X_pack and X_unpack functions implemented by CBinaryConverter
+	JSON          bool   // declare the X_from_json and X_to_json functions implemented by CJSONConverter
+	Lifecycle     bool   // declare the X_init, X_free, X_copy and X_equal functions implemented by CLifecycleConverter
+	Debug         bool   // declare the X_print and X_to_string functions implemented by CDebugConverter
+	Guard         string // "pragma" (the default) for #pragma once or "ifndef" for a classic include guard
+	GuardName     string // the macro defined by the ifndef guard
+	ExternC       bool   // wrap the declarations in extern "C" when compiled as c++
+	Constants     bool   // #define the array capacities and validate bounds of members
+	Source        bool   // also generate a .c file implementing the declared functions, see BuildFiles
+	BigEndian     bool   // the byte order of the pack and unpack functions in the .c file
+	CJSONInclude  string // how the .c file includes cJSON, defaults to "cJSON.h"
+	TypeIDs       string // the name of the type id enum and table, empty to leave them out
+	Fields        string // "xmacro" or "table" to describe the members of every struct, empty to leave them out
+}
+
+var cSingleArraySuffixRe = regexp.MustCompile(`^\[[\s]*[0-9]+[\s]*\]$`)
+
+var cGuardInvalidRe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// CGuardName derives an include guard macro from the output name, e.g. my-types becomes MY_TYPES_H
+func CGuardName(name string) string {
+	guard := cGuardInvalidRe.ReplaceAllString(strings.ToUpper(name), "_") + "_H"
+	if guard[0] >= '0' && guard[0] <= '9' {
+		guard = "_" + guard
+	}
+
+	return guard
+}
+
+// cBanner is the comment at the top of every generated c file
+func cBanner(inspecter *Inspecter) string {
+	var w strings.Builder
+	w.WriteString("/*\n")
+	w.WriteString(" * Code generated by go-struct-convert. DO NOT EDIT.\n")
+	if len(inspecter.Sources) > 0 {
+		w.WriteString(" *\n")
+		w.WriteString(" * Sources:\n")
+		for _, source := range inspecter.Sources {
+			w.WriteString(fmt.Sprintf(" *   %s\n", source))
+		}
+	}
+	w.WriteString(" */\n\n")
+
+	return w.String()
+}
+
+// CStructAttributes are the packing and alignment settings for a single struct
+type CStructAttributes struct {
+	Packed bool
+	Pack   int
+	Align  int
+}
+
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// structAttributes merges the converter wide settings with the
+// `//gsc:packed`, `//gsc:pack=N` and `//gsc:align=N` directives of a struct
+func (c *CConverter) structAttributes(cStruct Struct) (CStructAttributes, error) {
+	attrs := CStructAttributes{
+		Packed: c.Packed || cStruct.Directives.Has("packed"),
+		Pack:   c.Pack,
+	}
+
+	if cStruct.Directives.Has("pack") {
+		pack, err := cStruct.Directives.Int("pack")
+		if err != nil {
+			return attrs, err
+		}
+		attrs.Pack = pack
+	}
+
+	if attrs.Pack != 0 && (!isPowerOfTwo(attrs.Pack) || attrs.Pack > 16) {
+		return attrs, fmt.Errorf("%s: pack must be one of 1, 2, 4, 8 or 16, got %d", cStruct.Name, attrs.Pack)
+	}
+
+	align, err := cStruct.Directives.Int("align")
+	if err != nil {
+		return attrs, err
+	}
+
+	if align != 0 && !isPowerOfTwo(align) {
+		return attrs, fmt.Errorf("%s: align must be a power of two, got %d", cStruct.Name, align)
+	}
+	attrs.Align = align
+
+	return attrs, nil
+}
+
+// memberAlignment returns the alignment requested by a `calign:"N"` tag, 0 when there is none
+func memberAlignment(member StructMember) (int, error) {
+	if member.Tags == nil {
+		return 0, nil
+	}
+
+	tag, err := member.Tags.Get("calign")
+	if err != nil {
+		return 0, nil
+	}
+
+	align, err := strconv.Atoi(tag.Name)
+	if err != nil || !isPowerOfTwo(align) {
+		return 0, fmt.Errorf("%s: calign must be a power of two, got %q", member.Name, tag.Name)
+	}
+
+	return align, nil
+}
+
+// memberBits returns the bitfield width requested by a `cbits:"N"` tag, 0 when
+// there is none. The go field must be an integer or bool that can hold N bits.
+func memberBits(member StructMember) (int, error) {
+	if member.Tags == nil {
+		return 0, nil
+	}
+
+	tag, err := member.Tags.Get("cbits")
+	if err != nil {
+		return 0, nil
+	}
+
+	bits, err := strconv.Atoi(tag.Name)
+	if err != nil || bits <= 0 {
+		return 0, fmt.Errorf("%s: cbits must be a positive number, got %q", member.Name, tag.Name)
+	}
+
+	t := member.GoType
+	number, ok := goNumbers[t.GoValue]
+	if t.IsPointer || t.IsArray || t.IsMap || !ok || number.Kind == KindFloat {
+		return 0, fmt.Errorf("%s: cbits needs an integer or bool field", member.Name)
+	}
+
+	width := number.Size * 8
+	if number.Kind == KindBool {
+		width = 1
+	}
+
+	if bits > width {
+		return 0, fmt.Errorf("%s: %d bits do not fit in a %s", member.Name, bits, t.GoValue)
+	}
+
+	if member.Type.Suffix != "" || member.Type.IsPointer {
+		return 0, fmt.Errorf("%s: cbits cannot be combined with an array or pointer ctype", member.Name)
+	}
+
+	if size, ok := cScalarSizes[strings.TrimSpace(member.Type.Prefix+member.Type.Value)]; ok && bits > size*8 {
+		return 0, fmt.Errorf("%s: %d bits do not fit in a %s", member.Name, bits, member.Type.Value)
+	}
+
+	if _, err := member.Tags.Get("calign"); err == nil {
+		return 0, fmt.Errorf("%s: cbits cannot be combined with calign", member.Name)
+	}
+
+	return bits, nil
 }
 
 func (c *CConverter) GetIdent(s string) string {
@@ -79,43 +241,508 @@ func (c *CConverter) FileExtension() string {
 
 func (c *CConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
 	var err error
-	w.WriteString("#pragma once\n\n")
+	w.WriteString(cBanner(inspecter))
+
+	switch c.Guard {
+	case "", "pragma":
+		w.WriteString("#pragma once\n\n")
+	case "ifndef":
+		if c.GuardName == "" {
+			return errors.New("the ifndef include guard needs a name")
+		}
+		w.WriteString(fmt.Sprintf("#ifndef %s\n#define %s\n\n", c.GuardName, c.GuardName))
+	default:
+		return fmt.Errorf("unknown include guard %q, expected pragma or ifndef", c.Guard)
+	}
 
-	for _, include := range inspecter.Comments.CIncludes {
+	switch c.Fields {
+	case "", "xmacro", "table":
+	default:
+		return fmt.Errorf("unknown field table %q, expected xmacro or table", c.Fields)
+	}
+
+	includes := inspecter.Comments.CIncludes
+
+	usesAlignas := false
+	for _, cStruct := range inspecter.Structs {
+		for _, member := range cStruct.Members {
+			if member.Tags == nil {
+				continue
+			}
+			if _, err := member.Tags.Get("calign"); err == nil {
+				usesAlignas = true
+			}
+		}
+	}
+
+	usesSlices := false
+	for _, cStruct := range inspecter.Structs {
+		for _, member := range cStruct.Members {
+			if member.Type.IsArray || member.Type.IsMap {
+				usesSlices = true
+			}
+		}
+	}
+
+	if usesAlignas {
+		includes = appendCInclude(includes, "<stdalign.h>")
+	}
+
+	if usesSlices {
+		includes = appendCInclude(includes, "<stddef.h>")
+	}
+
+	if c.Binary {
+		includes = appendCInclude(includes, "<stddef.h>")
+		includes = appendCInclude(includes, "<stdint.h>")
+	}
+
+	if c.Debug {
+		includes = appendCInclude(includes, "<stddef.h>")
+		includes = appendCInclude(includes, "<stdio.h>")
+	}
+
+	if c.TypeIDs != "" || c.Fields != "" {
+		includes = appendCInclude(includes, "<stddef.h>")
+	}
+
+	var layouts map[string]CStructLayout
+	if c.StaticAsserts {
+		includes = appendCInclude(includes, "<assert.h>")
+		includes = appendCInclude(includes, "<stddef.h>")
+
+		layouts, err = c.Layout(inspecter)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, include := range includes {
 		w.WriteString(fmt.Sprintf("#include %s\n", include))
 	}
 
-	if len(inspecter.Comments.CIncludes) > 0 {
+	if len(includes) > 0 {
 		w.WriteString("\n")
 	}
 
 	w.WriteString("\n")
 
+	if c.ExternC {
+		w.WriteString("#ifdef __cplusplus\nextern \"C\" {\n#endif\n\n")
+	}
+
+	if c.JSON {
+		w.WriteString("struct cJSON;\n\n")
+	}
+
+	deprecations := cHasDeprecations(inspecter)
+	if deprecations {
+		// only warn where users refer to deprecated declarations, not in the declarations below
+		w.WriteString("#if defined(__GNUC__)\n#pragma GCC diagnostic push\n#pragma GCC diagnostic ignored \"-Wdeprecated-declarations\"\n#endif\n\n")
+	}
+
+	if c.Fields != "" {
+		c.fieldDescTypes(w, inspecter)
+	}
+
 	for _, cStruct := range inspecter.Structs {
-		w.WriteString("typedef struct {\n")
+		attrs, err := c.structAttributes(cStruct)
+		if err != nil {
+			return err
+		}
+
+		if c.Constants {
+			defined := false
+			for _, member := range cStruct.Members {
+				for _, constant := range MemberConstants(cStruct, member) {
+					w.WriteString(fmt.Sprintf("#define %s %s\n", constant.Name, constant.Value))
+					defined = true
+				}
+			}
+
+			if defined {
+				w.WriteString("\n")
+			}
+		}
+
+		for _, member := range cStruct.Members {
+			if !member.Type.IsMap || !c.supportedMap(member) {
+				continue
+			}
+
+			// maps become an array of key value pairs
+			w.WriteString("typedef struct {\n")
+			w.WriteString(fmt.Sprintf("%s%s\n", inspecter.Indent, c.declaration(*member.Type.MapKey, "key", inspecter.Indent)))
+			w.WriteString(fmt.Sprintf("%s%s\n", inspecter.Indent, c.declaration(*member.Type.MapVal, "value", inspecter.Indent)))
+			w.WriteString(fmt.Sprintf("} %s;\n\n", CMapEntryName(cStruct, member)))
+		}
+
+		for _, member := range cStruct.Members {
+			if len(member.OneOf) == 0 {
+				continue
+			}
+
+			// interface{} members with declared variants become a tagged union
+			w.WriteString("typedef enum {\n")
+			w.WriteString(fmt.Sprintf("%s%s = 0,\n", inspecter.Indent, COneOfKind(cStruct, member, "NONE")))
+			for _, variant := range member.OneOf {
+				w.WriteString(fmt.Sprintf("%s%s,\n", inspecter.Indent, COneOfKind(cStruct, member, variant.Name)))
+			}
+			w.WriteString(fmt.Sprintf("} %s_%s_kind;\n\n", cStruct.Name, member.Name))
+
+			w.WriteString("typedef struct {\n")
+			w.WriteString(fmt.Sprintf("%s%s_%s_kind kind;\n", inspecter.Indent, cStruct.Name, member.Name))
+			w.WriteString(fmt.Sprintf("%sunion {\n", inspecter.Indent))
+			for _, variant := range member.OneOf {
+				w.WriteString(fmt.Sprintf("%s%s%s %s;\n", inspecter.Indent, inspecter.Indent, variant.Type, COneOfMember(variant)))
+			}
+			w.WriteString(fmt.Sprintf("%s} value;\n", inspecter.Indent))
+			w.WriteString(fmt.Sprintf("} %s;\n\n", COneOfName(cStruct, member)))
+		}
+
+		if attrs.Pack > 0 {
+			w.WriteString(fmt.Sprintf("#pragma pack(push, %d)\n", attrs.Pack))
+		}
+
+		var attributes []string
+		if attrs.Packed {
+			attributes = append(attributes, "packed")
+		}
+		if attrs.Align > 0 {
+			attributes = append(attributes, fmt.Sprintf("aligned(%d)", attrs.Align))
+		}
+
+		w.WriteString(DocComment("", cStruct.Comment, cStruct.Deprecated))
+
+		if len(attributes) > 0 {
+			w.WriteString(fmt.Sprintf("typedef struct __attribute__((%s)) {\n", strings.Join(attributes, ", ")))
+		} else {
+			w.WriteString("typedef struct {\n")
+		}
 
 		for _, member := range cStruct.Members {
-			if member.Type.IsMap {
-				fmt.Fprintf(os.Stderr, "WARNING! maps are unsupported, skipping %s\n", member.Name)
+			if member.Type.IsMap && !c.supportedMap(member) {
+				fmt.Fprintf(os.Stderr, "WARNING! maps of maps are unsupported, skipping %s\n", member.Name)
 				continue
 			}
 
-			w.WriteString(fmt.Sprintf("%s%s%s ", inspecter.Indent, member.Type.Prefix, member.Type.Value))
-			if member.Type.IsPointer {
-				w.WriteString("*")
+			align, err := memberAlignment(member)
+			if err != nil {
+				return err
 			}
 
-			w.WriteString(fmt.Sprintf("%s%s;", member.Name, member.Type.Suffix))
+			bits, err := memberBits(member)
+			if err != nil {
+				return err
+			}
+
+			w.WriteString(DocComment(inspecter.Indent, member.Comment, member.Deprecated))
+
+			var decl string
+			if bits > 0 {
+				decl = fmt.Sprintf("%s%s %s : %d;", member.Type.Prefix, member.Type.Value, member.Name, bits)
+			} else if len(member.OneOf) > 0 {
+				decl = fmt.Sprintf("%s %s;", COneOfName(cStruct, member), member.Name)
+			} else if member.Type.IsMap {
+				decl = fmt.Sprintf("%s *%s;\n%ssize_t %s_len;", CMapEntryName(cStruct, member), member.Name, inspecter.Indent, member.Name)
+			} else {
+				t := member.Type
+				if c.Constants && cSingleArraySuffixRe.MatchString(t.Suffix) {
+					// size the array with its capacity constant so it is defined in one place
+					if _, ok := cTagCapacity(member); ok {
+						t.Suffix = fmt.Sprintf("[%s_%s_MAX]", ConstantCase(cStruct.Name), ConstantCase(member.Name))
+					}
+				}
+				decl = c.declaration(t, member.Name, inspecter.Indent)
+			}
 
-			if member.Comment != "" {
-				w.WriteString(fmt.Sprintf("%s// %s", inspecter.Indent, member.Comment))
+			if member.Deprecated != "" {
+				// deprecates the length of slices and maps too
+				decl = strings.ReplaceAll(decl, ";", cDeprecated(member.Deprecated)+";")
 			}
 
+			w.WriteString(inspecter.Indent)
+			if align > 0 {
+				w.WriteString(fmt.Sprintf("alignas(%d) ", align))
+			}
+			w.WriteString(decl)
 			w.WriteString("\n")
 		}
 
-		w.WriteString(fmt.Sprintf("} %s%s%s;\n\n", inspecter.Prefix, cStruct.Name, inspecter.Suffix))
+		w.WriteString(fmt.Sprintf("} %s%s;\n", cStruct.Name, cDeprecated(cStruct.Deprecated)))
+
+		if c.Binary {
+			w.WriteString(fmt.Sprintf("int %s_pack(const %s *in, uint8_t *buf, size_t len);\n", cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("int %s_unpack(%s *out, const uint8_t *buf, size_t len);\n", cStruct.Name, cStruct.Name))
+		}
+
+		if c.Lifecycle {
+			w.WriteString(fmt.Sprintf("void %s_init(%s *x);\n", cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("void %s_free(%s *x);\n", cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("int %s_copy(%s *dst, const %s *src);\n", cStruct.Name, cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("int %s_equal(const %s *a, const %s *b);\n", cStruct.Name, cStruct.Name, cStruct.Name))
+		}
+
+		if c.Debug {
+			w.WriteString(fmt.Sprintf("void %s_print(FILE *f, const %s *x, int indent);\n", cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("int %s_to_string(const %s *x, char *buf, size_t len);\n", cStruct.Name, cStruct.Name))
+		}
+
+		if c.JSON {
+			w.WriteString(fmt.Sprintf("int %s_from_json(const struct cJSON *json, %s *out);\n", cStruct.Name, cStruct.Name))
+			w.WriteString(fmt.Sprintf("struct cJSON *%s_to_json(const %s *in);\n", cStruct.Name, cStruct.Name))
+		}
+
+		if attrs.Pack > 0 {
+			w.WriteString("#pragma pack(pop)\n")
+		}
+
+		if c.StaticAsserts {
+			layout, ok := layouts[cStruct.Name]
+			if ok {
+				w.WriteString(fmt.Sprintf("static_assert(sizeof(%s) == %d, \"unexpected size for %s\");\n", cStruct.Name, layout.Size, cStruct.Name))
+				for _, member := range layout.Members {
+					if member.Bits > 0 {
+						// offsetof cannot be applied to bitfields
+						continue
+					}
+					w.WriteString(fmt.Sprintf("static_assert(offsetof(%s, %s) == %d, \"unexpected offset for %s.%s\");\n", cStruct.Name, member.Name, member.Offset, cStruct.Name, member.Name))
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "WARNING! unable to compute the layout of %s, skipping static asserts\n", cStruct.Name)
+			}
+		}
+
+		if c.Fields != "" {
+			err = c.fieldTable(w, inspecter, cStruct)
+			if err != nil {
+				return err
+			}
+		}
+
+		w.WriteString("\n")
+	}
+
+	if c.TypeIDs != "" {
+		ids, err := inspecter.TypeIDs()
+		if err != nil {
+			return err
+		}
+
+		c.typeIDTable(w, inspecter, ids)
+	}
+
+	if deprecations {
+		w.WriteString("#if defined(__GNUC__)\n#pragma GCC diagnostic pop\n#endif\n\n")
+	}
+
+	if c.ExternC {
+		w.WriteString("#ifdef __cplusplus\n}\n#endif\n\n")
+	}
+
+	if c.Guard == "ifndef" {
+		w.WriteString(fmt.Sprintf("#endif /* %s */\n", c.GuardName))
 	}
 
 	return err
 }
+
+// typeIDTable writes the enum of type ids and the functions returning the
+// {id, name, sizeof} table. The table lives in a function so headers that
+// never use it do not warn about an unused variable.
+func (c *CConverter) typeIDTable(w *strings.Builder, inspecter *Inspecter, ids []TypeID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	indent := inspecter.Indent
+	enum := c.TypeIDs + "_type_id"
+	info := c.TypeIDs + "_type_info"
+
+	w.WriteString("typedef enum {\n")
+	for _, id := range ids {
+		w.WriteString(fmt.Sprintf("%s%s = %d,\n", indent, TypeIDConstant(id.Struct), id.ID))
+	}
+	w.WriteString(fmt.Sprintf("} %s;\n\n", enum))
+
+	w.WriteString("typedef struct {\n")
+	w.WriteString(fmt.Sprintf("%s%s id;\n", indent, enum))
+	w.WriteString(fmt.Sprintf("%sconst char *name;\n", indent))
+	w.WriteString(fmt.Sprintf("%ssize_t size;\n", indent))
+	w.WriteString(fmt.Sprintf("} %s;\n\n", info))
+
+	w.WriteString("/* every struct with a type id, *len is set to the number of entries */\n")
+	w.WriteString(fmt.Sprintf("static inline const %s *%s_types(size_t *len)\n{\n", info, c.TypeIDs))
+	w.WriteString(fmt.Sprintf("%sstatic const %s types[] = {\n", indent, info))
+	for _, id := range ids {
+		w.WriteString(fmt.Sprintf("%s%s{ %s, \"%s\", sizeof(%s) },\n", indent, indent, TypeIDConstant(id.Struct), id.Struct.Name, id.Struct.Name))
+	}
+	w.WriteString(fmt.Sprintf("%s};\n\n", indent))
+	w.WriteString(fmt.Sprintf("%s*len = sizeof(types) / sizeof(types[0]);\n", indent))
+	w.WriteString(fmt.Sprintf("%sreturn types;\n", indent))
+	w.WriteString("}\n\n")
+
+	w.WriteString("/* the entry of the struct with the id, NULL when there is none */\n")
+	w.WriteString(fmt.Sprintf("static inline const %s *%s_type_lookup(%s id)\n{\n", info, c.TypeIDs, enum))
+	w.WriteString(fmt.Sprintf("%ssize_t len;\n", indent))
+	w.WriteString(fmt.Sprintf("%sconst %s *types = %s_types(&len);\n\n", indent, info, c.TypeIDs))
+	w.WriteString(fmt.Sprintf("%sfor (size_t i = 0; i < len; i++) {\n", indent))
+	w.WriteString(fmt.Sprintf("%s%sif (types[i].id == id) {\n", indent, indent))
+	w.WriteString(fmt.Sprintf("%s%s%sreturn &types[i];\n", indent, indent, indent))
+	w.WriteString(fmt.Sprintf("%s%s}\n", indent, indent))
+	w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+	w.WriteString(fmt.Sprintf("%sreturn NULL;\n", indent))
+	w.WriteString("}\n\n")
+}
+
+// CIdentifier turns an output name such as my-types into a c identifier (my_types)
+func CIdentifier(name string) string {
+	identifier := cIdentifierInvalidRe.ReplaceAllString(name, "_")
+	if identifier == "" || (identifier[0] >= '0' && identifier[0] <= '9') {
+		identifier = "_" + identifier
+	}
+
+	return identifier
+}
+
+var cIdentifierInvalidRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// BuildFiles generates the header and, when Source is set, a .c file holding
+// the functions the header declares with Binary, JSON, Lifecycle and Debug
+func (c *CConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	header := new(strings.Builder)
+	err := c.Builder(header, inspecter)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []OutputFile{{Name: name + ".h", Content: header.String()}}
+	if !c.Source {
+		return files, nil
+	}
+
+	var parts []Converter
+	if c.Binary {
+		parts = append(parts, &CBinaryConverter{BigEndian: c.BigEndian})
+	}
+	if c.JSON {
+		parts = append(parts, &CJSONConverter{CJSONInclude: c.CJSONInclude})
+	}
+	if c.Lifecycle {
+		parts = append(parts, &CLifecycleConverter{})
+	}
+	if c.Debug {
+		parts = append(parts, &CDebugConverter{})
+	}
+
+	if len(parts) == 0 {
+		return nil, errors.New("a source file needs binary, json, lifecycle or debug functions to implement")
+	}
+
+	// every part starts with the same banner, keep only the first
+	prologue := cBanner(inspecter) + cIgnoreDeprecations(inspecter)
+
+	source := new(strings.Builder)
+	source.WriteString(prologue)
+	source.WriteString(fmt.Sprintf("#include \"%s.h\"\n\n", name))
+	for _, part := range parts {
+		var b strings.Builder
+		err := part.Builder(&b, inspecter)
+		if err != nil {
+			return nil, err
+		}
+
+		source.WriteString(strings.TrimPrefix(b.String(), prologue))
+	}
+
+	return append(files, OutputFile{Name: name + ".c", Content: source.String()}), nil
+}
+
+// cDeprecated is the attribute marking a declaration deprecated, empty unless it is
+func cDeprecated(deprecated string) string {
+	if deprecated == "" {
+		return ""
+	}
+
+	message := DeprecatedMessage(deprecated)
+	if message == "" {
+		return " __attribute__((deprecated))"
+	}
+
+	return fmt.Sprintf(" __attribute__((deprecated(%s)))", strconv.Quote(message))
+}
+
+func cHasDeprecations(inspecter *Inspecter) bool {
+	for _, cStruct := range inspecter.Structs {
+		if cStruct.Deprecated != "" {
+			return true
+		}
+
+		for _, member := range cStruct.Members {
+			if member.Deprecated != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// cIgnoreDeprecations keeps the generated c sources from warning about the
+// deprecated structs and members they have to handle
+func cIgnoreDeprecations(inspecter *Inspecter) string {
+	if !cHasDeprecations(inspecter) {
+		return ""
+	}
+
+	return "#if defined(__GNUC__)\n#pragma GCC diagnostic ignored \"-Wdeprecated-declarations\"\n#endif\n\n"
+}
+
+// CMapEntryName is the name of the key value pair struct a map member is converted to
+func CMapEntryName(cStruct Struct, member StructMember) string {
+	return fmt.Sprintf("%s_%s_entry", cStruct.Name, member.Name)
+}
+
+// COneOfName is the name of the tagged union struct an interface{} member with a oneof tag is converted to
+func COneOfName(cStruct Struct, member StructMember) string {
+	return fmt.Sprintf("%s_%s_oneof", cStruct.Name, member.Name)
+}
+
+// COneOfKind is the enum constant identifying a variant of a tagged union
+func COneOfKind(cStruct Struct, member StructMember, variant string) string {
+	return fmt.Sprintf("%s_%s_%s", cStruct.Name, member.Name, variant)
+}
+
+// COneOfMember is the name of a variant within the union, lower cased so it
+// never shares the name of its type (which c++ compilers reject)
+func COneOfMember(variant OneOfVariant) string {
+	return strings.ToLower(variant.Name[:1]) + variant.Name[1:]
+}
+
+func (c *CConverter) supportedMap(member StructMember) bool {
+	return member.Type.MapKey != nil && member.Type.MapVal != nil && !member.Type.MapVal.IsMap
+}
+
+// declaration returns the c declaration of a variable, slices become a
+// pointer to the elements followed by a second variable holding their count
+func (c *CConverter) declaration(t StructMemberType, name string, indent string) string {
+	decl := fmt.Sprintf("%s%s ", t.Prefix, t.Value)
+	if t.IsPointer {
+		decl += "*"
+	}
+
+	if t.IsArray {
+		return fmt.Sprintf("%s*%s%s;\n%ssize_t %s_len;", decl, name, t.Suffix, indent, name)
+	}
+
+	return fmt.Sprintf("%s%s%s;", decl, name, t.Suffix)
+}
+
+// appendCInclude adds an include unless it is already present
+func appendCInclude(includes []string, include string) []string {
+	if lo.Contains(includes, include) {
+		return includes
+	}
+
+	return append(includes, include)
+}
@@ -0,0 +1,374 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CBinaryConverter generates the c source implementing the X_pack and
+// X_unpack functions declared by the c header when CConverter.Binary is set
+type CBinaryConverter struct {
+	CConverter
+	Header    string // the generated header to include
+	BigEndian bool
+}
+
+func (c *CBinaryConverter) FileExtension() string {
+	return "c"
+}
+
+// BuildFiles generates only the .c file, the embedded CConverter would also
+// generate the header
+func (c *CBinaryConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	return buildFile(c, name, inspecter)
+}
+
+const cBinaryHelpers = `typedef struct {
+	uint8_t *buf;
+	size_t len;
+	size_t pos;
+	int err;
+} gsc_writer_t;
+
+typedef struct {
+	const uint8_t *buf;
+	size_t len;
+	size_t pos;
+	int err;
+} gsc_reader_t;
+
+static inline void gsc_put(gsc_writer_t *w, uint64_t v, size_t size)
+{
+	size_t i;
+
+	if (w->buf != NULL) {
+		if (w->err || w->len - w->pos < size) {
+			w->err = 1;
+			return;
+		}
+
+		for (i = 0; i < size; i++) {
+			w->buf[w->pos + i] = (uint8_t)(v >> (8 * %s));
+		}
+	}
+
+	w->pos += size;
+}
+
+static inline void gsc_put_f32(gsc_writer_t *w, float v)
+{
+	uint32_t u;
+	memcpy(&u, &v, sizeof(u));
+	gsc_put(w, u, 4);
+}
+
+static inline void gsc_put_f64(gsc_writer_t *w, double v)
+{
+	uint64_t u;
+	memcpy(&u, &v, sizeof(u));
+	gsc_put(w, u, 8);
+}
+
+static inline void gsc_put_str(gsc_writer_t *w, const char *s, size_t max)
+{
+	size_t n = 0;
+
+	if (s != NULL) {
+		while (n < max && s[n] != '\0') {
+			n++;
+		}
+	}
+
+	gsc_put(w, n, 4);
+
+	if (w->buf != NULL && n > 0) {
+		if (w->err || w->len - w->pos < n) {
+			w->err = 1;
+			return;
+		}
+
+		memcpy(w->buf + w->pos, s, n);
+	}
+
+	w->pos += n;
+}
+
+static inline uint64_t gsc_get(gsc_reader_t *r, size_t size)
+{
+	uint64_t v = 0;
+	size_t i;
+
+	if (r->err || r->len - r->pos < size) {
+		r->err = 1;
+		return 0;
+	}
+
+	for (i = 0; i < size; i++) {
+		v |= (uint64_t)r->buf[r->pos + i] << (8 * %s);
+	}
+
+	r->pos += size;
+	return v;
+}
+
+static inline float gsc_get_f32(gsc_reader_t *r)
+{
+	uint32_t u = (uint32_t)gsc_get(r, 4);
+	float v;
+	memcpy(&v, &u, sizeof(v));
+	return v;
+}
+
+static inline double gsc_get_f64(gsc_reader_t *r)
+{
+	uint64_t u = gsc_get(r, 8);
+	double v;
+	memcpy(&v, &u, sizeof(v));
+	return v;
+}
+
+static char *gsc_get_str(gsc_reader_t *r)
+{
+	size_t n = (size_t)gsc_get(r, 4);
+	char *s;
+
+	if (r->err || r->len - r->pos < n) {
+		r->err = 1;
+		return NULL;
+	}
+
+	s = (char *)malloc(n + 1);
+	if (s == NULL) {
+		r->err = 1;
+		return NULL;
+	}
+
+	memcpy(s, r->buf + r->pos, n);
+	s[n] = '\0';
+	r->pos += n;
+	return s;
+}
+
+static inline void gsc_get_strn(gsc_reader_t *r, char *s, size_t size)
+{
+	size_t n = (size_t)gsc_get(r, 4);
+
+	if (r->err || n >= size || r->len - r->pos < n) {
+		r->err = 1;
+		return;
+	}
+
+	memcpy(s, r->buf + r->pos, n);
+	s[n] = '\0';
+	r->pos += n;
+}
+
+static inline size_t gsc_get_count(gsc_reader_t *r, size_t min_size)
+{
+	size_t n = (size_t)gsc_get(r, 4);
+
+	// every element takes at least min_size bytes, reject counts that cannot fit
+	if (min_size > 0 && n > (r->len - r->pos) / min_size) {
+		r->err = 1;
+		return 0;
+	}
+
+	return n;
+}
+
+`
+
+func (c *CBinaryConverter) writeValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, bt *ValueType) {
+	switch bt.Kind {
+	case KindBool:
+		w.WriteString(fmt.Sprintf("%sgsc_put(w, (%s) ? 1 : 0, 1);\n", indent, expr))
+	case KindInt, KindTime:
+		w.WriteString(fmt.Sprintf("%sgsc_put(w, (uint64_t)(int64_t)(%s), %d);\n", indent, expr, bt.Size))
+	case KindUint:
+		w.WriteString(fmt.Sprintf("%sgsc_put(w, (uint64_t)(%s), %d);\n", indent, expr, bt.Size))
+	case KindFloat:
+		w.WriteString(fmt.Sprintf("%sgsc_put_f%d(w, %s);\n", indent, bt.Size*8, expr))
+	case KindString:
+		max := "SIZE_MAX"
+		if bt.Fixed > 0 {
+			max = fmt.Sprintf("%d", bt.Fixed-1)
+		}
+		w.WriteString(fmt.Sprintf("%sgsc_put_str(w, %s, %s);\n", indent, expr, max))
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%sgsc_write_%s(w, &%s);\n", indent, bt.Struct.Name, expr))
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sgsc_put(w, %s != NULL, 1);\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sif (%s != NULL) {\n", indent, expr))
+		c.writeValue(w, indent+inspecter.Indent, inspecter, fmt.Sprintf("(*%s)", expr), bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (c *CBinaryConverter) readValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, bt *ValueType) {
+	switch bt.Kind {
+	case KindBool:
+		w.WriteString(fmt.Sprintf("%s%s = gsc_get(r, 1) != 0;\n", indent, expr))
+	case KindInt, KindTime:
+		w.WriteString(fmt.Sprintf("%s%s = (int%d_t)(uint%d_t)gsc_get(r, %d);\n", indent, expr, bt.Size*8, bt.Size*8, bt.Size))
+	case KindUint:
+		w.WriteString(fmt.Sprintf("%s%s = (uint%d_t)gsc_get(r, %d);\n", indent, expr, bt.Size*8, bt.Size))
+	case KindFloat:
+		w.WriteString(fmt.Sprintf("%s%s = gsc_get_f%d(r);\n", indent, expr, bt.Size*8))
+	case KindString:
+		if bt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%sgsc_get_strn(r, %s, %d);\n", indent, expr, bt.Fixed))
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = gsc_get_str(r);\n", indent, expr))
+		}
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%sgsc_read_%s(r, &%s);\n", indent, bt.Struct.Name, expr))
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%s%s = NULL;\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sif (gsc_get(r, 1) != 0) {\n", indent))
+		w.WriteString(fmt.Sprintf("%s%s%s = calloc(1, sizeof(*%s));\n", indent, inspecter.Indent, expr, expr))
+		w.WriteString(fmt.Sprintf("%s%sif (%s == NULL) {\n", indent, inspecter.Indent, expr))
+		w.WriteString(fmt.Sprintf("%s%s%sr->err = 1;\n", indent, inspecter.Indent, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s%s%sreturn;\n", indent, inspecter.Indent, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s%s}\n", indent, inspecter.Indent))
+		c.readValue(w, indent+inspecter.Indent, inspecter, fmt.Sprintf("(*%s)", expr), bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (c *CBinaryConverter) writeMember(w *strings.Builder, inspecter *Inspecter, member StructMember, bt *ValueType) {
+	indent := inspecter.Indent
+	expr := fmt.Sprintf("in->%s", member.Name)
+
+	if bt.Kind != KindSlice {
+		c.writeValue(w, indent, inspecter, expr, bt)
+		return
+	}
+
+	count := fmt.Sprintf("%d", bt.Fixed)
+	if bt.Fixed == 0 {
+		count = fmt.Sprintf("in->%s_len", member.Name)
+	}
+
+	w.WriteString(fmt.Sprintf("%sgsc_put(w, (uint64_t)%s, 4);\n", indent, count))
+	w.WriteString(fmt.Sprintf("%sfor (size_t i = 0; i < %s; i++) {\n", indent, count))
+	c.writeValue(w, indent+inspecter.Indent, inspecter, expr+"[i]", bt.Elem)
+	w.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+func (c *CBinaryConverter) readMember(w *strings.Builder, inspecter *Inspecter, member StructMember, bt *ValueType) {
+	indent := inspecter.Indent
+	inner := indent + inspecter.Indent
+	expr := fmt.Sprintf("out->%s", member.Name)
+
+	if bt.Kind != KindSlice {
+		c.readValue(w, indent, inspecter, expr, bt)
+		return
+	}
+
+	minSize := inspecter.BinaryMinSize(bt.Elem)
+
+	if bt.Fixed > 0 {
+		w.WriteString(fmt.Sprintf("%s{\n", indent))
+		w.WriteString(fmt.Sprintf("%ssize_t n = gsc_get_count(r, %d);\n", inner, minSize))
+		w.WriteString(fmt.Sprintf("%sif (n > %d) {\n", inner, bt.Fixed))
+		w.WriteString(fmt.Sprintf("%s%sr->err = 1;\n", inner, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s%sreturn;\n", inner, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%sfor (size_t i = 0; i < n; i++) {\n", inner))
+		c.readValue(w, inner+inspecter.Indent, inspecter, expr+"[i]", bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+		return
+	}
+
+	w.WriteString(fmt.Sprintf("%s%s = NULL;\n", indent, expr))
+	w.WriteString(fmt.Sprintf("%s%s_len = gsc_get_count(r, %d);\n", indent, expr, minSize))
+	w.WriteString(fmt.Sprintf("%sif (%s_len > 0) {\n", indent, expr))
+	w.WriteString(fmt.Sprintf("%s%s = calloc(%s_len, sizeof(*%s));\n", inner, expr, expr, expr))
+	w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", inner, expr))
+	w.WriteString(fmt.Sprintf("%s%s%s_len = 0;\n", inner, inspecter.Indent, expr))
+	w.WriteString(fmt.Sprintf("%s%sr->err = 1;\n", inner, inspecter.Indent))
+	w.WriteString(fmt.Sprintf("%s%sreturn;\n", inner, inspecter.Indent))
+	w.WriteString(fmt.Sprintf("%s}\n", inner))
+	w.WriteString(fmt.Sprintf("%sfor (size_t i = 0; i < %s_len; i++) {\n", inner, expr))
+	c.readValue(w, inner+inspecter.Indent, inspecter, expr+"[i]", bt.Elem)
+	w.WriteString(fmt.Sprintf("%s}\n", inner))
+	w.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+func (c *CBinaryConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	w.WriteString(cBanner(inspecter))
+	w.WriteString(cIgnoreDeprecations(inspecter))
+
+	if c.Header != "" {
+		w.WriteString(fmt.Sprintf("#include \"%s\"\n\n", c.Header))
+	}
+
+	w.WriteString("#include <stdint.h>\n")
+	w.WriteString("#include <stdlib.h>\n")
+	w.WriteString("#include <string.h>\n")
+	w.WriteString("#include <limits.h>\n\n")
+
+	// the byte order decides which byte of the value lands at each offset
+	shift := "i"
+	if c.BigEndian {
+		shift = "(size - 1 - i)"
+	}
+	w.WriteString(fmt.Sprintf(cBinaryHelpers, shift, shift))
+
+	for _, cStruct := range inspecter.Structs {
+		w.WriteString(fmt.Sprintf("static void gsc_write_%s(gsc_writer_t *w, const %s *in);\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("static void gsc_read_%s(gsc_reader_t *r, %s *out);\n", cStruct.Name, cStruct.Name))
+	}
+	w.WriteString("\n")
+
+	for _, cStruct := range inspecter.Structs {
+		var members []StructMember
+		var types []*ValueType
+
+		for _, member := range cStruct.Members {
+			bt, err := inspecter.BinaryMemberType(member)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING! %s, skipping %s.%s\n", err, cStruct.Name, member.Name)
+				continue
+			}
+
+			members = append(members, member)
+			types = append(types, bt)
+		}
+
+		w.WriteString(fmt.Sprintf("static void gsc_write_%s(gsc_writer_t *w, const %s *in)\n{\n", cStruct.Name, cStruct.Name))
+		if len(members) == 0 {
+			w.WriteString(fmt.Sprintf("%s(void)w;\n%s(void)in;\n", inspecter.Indent, inspecter.Indent))
+		}
+		for i, member := range members {
+			c.writeMember(w, inspecter, member, types[i])
+		}
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("static void gsc_read_%s(gsc_reader_t *r, %s *out)\n{\n", cStruct.Name, cStruct.Name))
+		if len(members) == 0 {
+			w.WriteString(fmt.Sprintf("%s(void)r;\n%s(void)out;\n", inspecter.Indent, inspecter.Indent))
+		}
+		for i, member := range members {
+			c.readMember(w, inspecter, member, types[i])
+		}
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("int %s_pack(const %s *in, uint8_t *buf, size_t len)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_writer_t w = { buf, len, 0, 0 };\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sgsc_write_%s(&w, in);\n", inspecter.Indent, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sreturn (w.err || w.pos > INT_MAX) ? -1 : (int)w.pos;\n", inspecter.Indent))
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("int %s_unpack(%s *out, const uint8_t *buf, size_t len)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_reader_t r = { buf, len, 0, 0 };\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%smemset(out, 0, sizeof(*out));\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sgsc_read_%s(&r, out);\n", inspecter.Indent, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sreturn (r.err || r.pos > INT_MAX) ? -1 : (int)r.pos;\n", inspecter.Indent))
+		w.WriteString("}\n\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeInput(t *testing.T, source string) string {
+	t.Helper()
+
+	input := filepath.Join(t.TempDir(), "input.go")
+	if err := os.WriteFile(input, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return input
+}
+
+func TestCBinaryBuildFilesWritesSource(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Point struct {
+	X int32
+	Y int32
+}
+`)
+
+	inspecter := &Inspecter{Converter: &CBinaryConverter{Header: "Points.h"}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "Points")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 || files[0].Name != "Points.c" {
+		t.Fatalf("expected only Points.c, got %v", files)
+	}
+
+	for _, fn := range []string{"Point_pack(", "Point_unpack("} {
+		if !strings.Contains(files[0].Content, fn) {
+			t.Errorf("Points.c does not define %s", fn)
+		}
+	}
+}
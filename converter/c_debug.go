@@ -0,0 +1,233 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CDebugConverter generates the c source implementing the X_print and
+// X_to_string functions declared by the c header when CConverter.Debug is
+// set. Every member is printed by name, nested structs and arrays are
+// printed recursively with two spaces per indent level.
+type CDebugConverter struct {
+	CConverter
+	Header string // the generated header to include
+}
+
+func (c *CDebugConverter) FileExtension() string {
+	return "c"
+}
+
+// BuildFiles generates only the .c file, the embedded CConverter would also
+// generate the header
+func (c *CDebugConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	return buildFile(c, name, inspecter)
+}
+
+const cDebugHelpers = `typedef struct {
+	FILE *f;
+	char *buf;
+	size_t len;
+	size_t pos;
+} gsc_printer_t;
+
+// writes to the file or appends to the buffer, counting what would have been written like snprintf
+static inline void gsc_printf(gsc_printer_t *p, const char *format, ...)
+{
+	va_list ap;
+	int n;
+
+	va_start(ap, format);
+	if (p->f != NULL) {
+		n = vfprintf(p->f, format, ap);
+	} else if (p->pos < p->len) {
+		n = vsnprintf(p->buf + p->pos, p->len - p->pos, format, ap);
+	} else {
+		n = vsnprintf(NULL, 0, format, ap);
+	}
+	va_end(ap);
+
+	if (n > 0) {
+		p->pos += (size_t)n;
+	}
+}
+
+static inline void gsc_print_indent(gsc_printer_t *p, int indent)
+{
+	gsc_printf(p, "%*s", indent * 2, "");
+}
+
+static inline void gsc_print_field(gsc_printer_t *p, int indent, const char *name)
+{
+	gsc_print_indent(p, indent);
+	gsc_printf(p, "%s: ", name);
+}
+
+static inline void gsc_print_string(gsc_printer_t *p, const char *s)
+{
+	if (s == NULL) {
+		gsc_printf(p, "NULL");
+	} else {
+		gsc_printf(p, "\"%s\"", s);
+	}
+}
+
+`
+
+// cInlinePrint is whether a value is printed on a single line
+func cInlinePrint(vt *ValueType) bool {
+	switch vt.Kind {
+	case KindStruct, KindSlice, KindMap:
+		return false
+	case KindPointer:
+		return cInlinePrint(vt.Elem)
+	}
+
+	return true
+}
+
+func cPrintLevel(level int) string {
+	if level == 0 {
+		return "indent"
+	}
+
+	return fmt.Sprintf("indent + %d", level)
+}
+
+// printValue writes the statements printing expr, multi line values end
+// with their closing brace at the given indent level
+func (c *CDebugConverter) printValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, vt *ValueType, level int, depth int) {
+	inner := indent + inspecter.Indent
+
+	switch vt.Kind {
+	case KindBool:
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%%s\", %s ? \"true\" : \"false\");\n", indent, expr))
+	case KindInt:
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%%lld\", (long long)%s);\n", indent, expr))
+	case KindUint:
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%%llu\", (unsigned long long)%s);\n", indent, expr))
+	case KindFloat:
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%%g\", (double)%s);\n", indent, expr))
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%%lld ns\", (long long)%s);\n", indent, expr))
+	case KindString:
+		if vt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"\\\"%%.*s\\\"\", %d, %s);\n", indent, vt.Fixed, expr))
+		} else {
+			w.WriteString(fmt.Sprintf("%sgsc_print_string(p, %s);\n", indent, expr))
+		}
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%sgsc_print_%s(p, &%s, %s);\n", indent, vt.Struct.Name, expr, cPrintLevel(level)))
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"NULL\");\n", inner))
+		w.WriteString(fmt.Sprintf("%s} else {\n", indent))
+		c.printValue(w, inner, inspecter, fmt.Sprintf("(*%s)", expr), vt.Elem, level, depth)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice, KindMap:
+		index := fmt.Sprintf("i%d", depth)
+		count := expr + "_len"
+		if vt.Fixed > 0 {
+			count = fmt.Sprintf("%d", vt.Fixed)
+		}
+
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"[%%lu] {\", (unsigned long)%s);\n", indent, count))
+		w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", indent, index, index, count, index))
+
+		elem := fmt.Sprintf("%s[%s]", expr, index)
+		if vt.Kind == KindSlice && cInlinePrint(vt.Elem) {
+			// short values stay on one line
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, %s == 0 ? \" \" : \", \");\n", inner, index))
+			c.printValue(w, inner, inspecter, elem, vt.Elem, level+1, depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, \" }\");\n", indent))
+			return
+		}
+
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"\\n\");\n", inner))
+		w.WriteString(fmt.Sprintf("%sgsc_print_indent(p, %s);\n", inner, cPrintLevel(level+1)))
+		if vt.Kind == KindMap {
+			c.printValue(w, inner, inspecter, elem+".key", vt.Key, level+1, depth+1)
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, \": \");\n", inner))
+			elem += ".value"
+		} else {
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"[%%lu]: \", (unsigned long)%s);\n", inner, index))
+		}
+		c.printValue(w, inner, inspecter, elem, vt.Elem, level+1, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"\\n\");\n", indent))
+		w.WriteString(fmt.Sprintf("%sgsc_print_indent(p, %s);\n", indent, cPrintLevel(level)))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"}\");\n", indent))
+	}
+}
+
+func (c *CDebugConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	w.WriteString(cBanner(inspecter))
+	w.WriteString(cIgnoreDeprecations(inspecter))
+
+	if c.Header != "" {
+		w.WriteString(fmt.Sprintf("#include \"%s\"\n\n", c.Header))
+	}
+
+	w.WriteString("#include <limits.h>\n")
+	w.WriteString("#include <stdarg.h>\n")
+	w.WriteString("#include <stdio.h>\n")
+	w.WriteString("#include <string.h>\n\n")
+
+	w.WriteString(cDebugHelpers)
+
+	for _, cStruct := range inspecter.Structs {
+		w.WriteString(fmt.Sprintf("static void gsc_print_%s(gsc_printer_t *p, const %s *x, int indent);\n", cStruct.Name, cStruct.Name))
+	}
+	w.WriteString("\n")
+
+	for _, cStruct := range inspecter.Structs {
+		indent := inspecter.Indent
+
+		w.WriteString(fmt.Sprintf("static void gsc_print_%s(gsc_printer_t *p, const %s *x, int indent)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"%s {\\n\");\n", indent, cStruct.Name))
+
+		printed := 0
+		for _, member := range cStruct.Members {
+			if member.Type.IsMap && !c.supportedMap(member) {
+				continue
+			}
+
+			vt, err := inspecter.MemberValueType(member)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING! %s, skipping %s.%s\n", err, cStruct.Name, member.Name)
+				continue
+			}
+
+			w.WriteString(fmt.Sprintf("%sgsc_print_field(p, indent + 1, \"%s\");\n", indent, member.Name))
+			c.printValue(w, indent, inspecter, "x->"+member.Name, vt, 1, 0)
+			w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"\\n\");\n", indent))
+			printed++
+		}
+
+		if printed == 0 {
+			w.WriteString(fmt.Sprintf("%s(void)x;\n", indent))
+		}
+
+		w.WriteString(fmt.Sprintf("%sgsc_print_indent(p, indent);\n", indent))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(p, \"}\");\n", indent))
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("void %s_print(FILE *f, const %s *x, int indent)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_printer_t p = { f, NULL, 0, 0 };\n", indent))
+		w.WriteString(fmt.Sprintf("%sgsc_print_indent(&p, indent);\n", indent))
+		w.WriteString(fmt.Sprintf("%sgsc_print_%s(&p, x, indent);\n", indent, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_printf(&p, \"\\n\");\n", indent))
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("int %s_to_string(const %s *x, char *buf, size_t len)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sgsc_printer_t p = { NULL, buf, len, 0 };\n", indent))
+		w.WriteString(fmt.Sprintf("%sif (len > 0) {\n%s%sbuf[0] = '\\0';\n%s}\n", indent, indent, indent, indent))
+		w.WriteString(fmt.Sprintf("%sgsc_print_%s(&p, x, 0);\n", indent, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sreturn p.pos > INT_MAX ? -1 : (int)p.pos;\n", indent))
+		w.WriteString("}\n\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cFieldTypes are the codes of the gsc_field_type_t enum, in declaration order
+var cFieldTypes = []string{
+	"UNKNOWN",
+	"BOOL",
+	"CHAR",
+	"INT8",
+	"INT16",
+	"INT32",
+	"INT64",
+	"UINT8",
+	"UINT16",
+	"UINT32",
+	"UINT64",
+	"FLOAT",
+	"DOUBLE",
+	"SIZE",
+	"STRING",
+	"STRUCT",
+	"POINTER",
+	"SLICE",
+	"MAP",
+	"ONEOF",
+}
+
+var cScalarFieldTypes = map[string]string{
+	"bool":               "BOOL",
+	"_Bool":              "BOOL",
+	"bool_t":             "BOOL",
+	"char":               "CHAR",
+	"signed char":        "INT8",
+	"int8_t":             "INT8",
+	"unsigned char":      "UINT8",
+	"uint8_t":            "UINT8",
+	"short":              "INT16",
+	"int16_t":            "INT16",
+	"unsigned short":     "UINT16",
+	"uint16_t":           "UINT16",
+	"int":                "INT32",
+	"int32_t":            "INT32",
+	"unsigned int":       "UINT32",
+	"uint32_t":           "UINT32",
+	"long long":          "INT64",
+	"int64_t":            "INT64",
+	"unsigned long long": "UINT64",
+	"uint64_t":           "UINT64",
+	"float":              "FLOAT",
+	"double":             "DOUBLE",
+	"size_t":             "SIZE",
+}
+
+// CFieldDesc describes a member of a generated c struct for the field tables
+type CFieldDesc struct {
+	Name  string
+	Type  string // the gsc_field_type_t code, e.g. GSC_FIELD_INT32
+	Count int    // elements of fixed size arrays, 1 for single values and 0 for slices and maps
+}
+
+func cFieldType(code string) string {
+	return "GSC_FIELD_" + code
+}
+
+// cScalarFieldType returns the code of a c type without array suffix
+func (c *CConverter) cScalarFieldType(inspecter *Inspecter, t StructMemberType) string {
+	base := strings.TrimSpace(t.Prefix + t.Value)
+
+	if base == "char *" || base == "char*" {
+		return "STRING"
+	}
+
+	if strings.HasSuffix(base, "*") {
+		return "POINTER"
+	}
+
+	if code, ok := cScalarFieldTypes[base]; ok {
+		return code
+	}
+
+	for _, cStruct := range inspecter.Structs {
+		if cStruct.Name == base {
+			return "STRUCT"
+		}
+	}
+
+	return "UNKNOWN"
+}
+
+// FieldDescs returns the members of a struct as the c compiler sees them.
+// Slices and maps are followed by their _len count and bitfields are left
+// out because offsetof and sizeof cannot be applied to them.
+func (c *CConverter) FieldDescs(inspecter *Inspecter, cStruct Struct) ([]CFieldDesc, error) {
+	var fields []CFieldDesc
+
+	for _, member := range cStruct.Members {
+		if member.Type.IsMap && !c.supportedMap(member) {
+			continue
+		}
+
+		bits, err := memberBits(member)
+		if err != nil {
+			return nil, err
+		}
+
+		if bits > 0 {
+			fmt.Fprintf(os.Stderr, "WARNING! bitfields have no offset, leaving %s.%s out of the field table\n", cStruct.Name, member.Name)
+			continue
+		}
+
+		t := member.Type
+		switch {
+		case len(member.OneOf) > 0:
+			fields = append(fields, CFieldDesc{Name: member.Name, Type: cFieldType("ONEOF"), Count: 1})
+		case t.IsMap || t.IsArray:
+			code := "SLICE"
+			if t.IsMap {
+				code = "MAP"
+			}
+
+			fields = append(fields, CFieldDesc{Name: member.Name, Type: cFieldType(code)})
+			fields = append(fields, CFieldDesc{Name: member.Name + "_len", Type: cFieldType("SIZE"), Count: 1})
+		case t.IsPointer:
+			fields = append(fields, CFieldDesc{Name: member.Name, Type: cFieldType("POINTER"), Count: 1})
+		default:
+			count := 1
+			if t.Suffix != "" {
+				count, err = cArrayCount(t.Suffix)
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid array suffix %q", member.Name, t.Suffix)
+				}
+			}
+
+			fields = append(fields, CFieldDesc{Name: member.Name, Type: cFieldType(c.cScalarFieldType(inspecter, t)), Count: count})
+		}
+	}
+
+	return fields, nil
+}
+
+// fieldDescTypes writes the gsc_field_type_t enum and gsc_field_desc_t
+// struct, guarded so several generated headers can be included together
+func (c *CConverter) fieldDescTypes(w *strings.Builder, inspecter *Inspecter) {
+	indent := inspecter.Indent
+
+	w.WriteString("#ifndef GSC_FIELD_DESC_DEFINED\n#define GSC_FIELD_DESC_DEFINED\n\n")
+
+	w.WriteString("/* the c type of a struct member, fixed size arrays have the type of their elements */\n")
+	w.WriteString("typedef enum {\n")
+	for i, code := range cFieldTypes {
+		if i == 0 {
+			w.WriteString(fmt.Sprintf("%s%s = 0,\n", indent, cFieldType(code)))
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s,\n", indent, cFieldType(code)))
+		}
+	}
+	w.WriteString("} gsc_field_type_t;\n\n")
+
+	w.WriteString("typedef struct {\n")
+	w.WriteString(fmt.Sprintf("%sconst char *name;\n", indent))
+	w.WriteString(fmt.Sprintf("%sgsc_field_type_t type;\n", indent))
+	w.WriteString(fmt.Sprintf("%ssize_t offset;\n", indent))
+	w.WriteString(fmt.Sprintf("%ssize_t size;\n", indent))
+	w.WriteString(fmt.Sprintf("%ssize_t count; /* elements of fixed size arrays, 1 for single values and 0 for slices and maps */\n", indent))
+	w.WriteString("} gsc_field_desc_t;\n\n")
+
+	w.WriteString("#endif /* GSC_FIELD_DESC_DEFINED */\n\n")
+}
+
+// fieldTable writes the field metadata of a struct, either as an X-macro
+// calling X(struct, member, type, count) for every member or as a function
+// returning a gsc_field_desc_t array
+func (c *CConverter) fieldTable(w *strings.Builder, inspecter *Inspecter, cStruct Struct) error {
+	fields, err := c.FieldDescs(inspecter, cStruct)
+	if err != nil {
+		return err
+	}
+
+	indent := inspecter.Indent
+	w.WriteString("\n")
+
+	switch c.Fields {
+	case "xmacro":
+		w.WriteString(fmt.Sprintf("#define %s_FIELDS(X)", ConstantCase(cStruct.Name)))
+		for _, field := range fields {
+			w.WriteString(fmt.Sprintf(" \\\n%sX(%s, %s, %s, %d)", indent, cStruct.Name, field.Name, field.Type, field.Count))
+		}
+		w.WriteString("\n")
+	case "table":
+		w.WriteString(fmt.Sprintf("static inline const gsc_field_desc_t *%s_fields(size_t *len)\n{\n", cStruct.Name))
+		if len(fields) == 0 {
+			w.WriteString(fmt.Sprintf("%s*len = 0;\n", indent))
+			w.WriteString(fmt.Sprintf("%sreturn NULL;\n", indent))
+			w.WriteString("}\n")
+			return nil
+		}
+
+		w.WriteString(fmt.Sprintf("%sstatic const gsc_field_desc_t fields[] = {\n", indent))
+		for _, field := range fields {
+			w.WriteString(fmt.Sprintf("%s%s{ \"%s\", %s, offsetof(%s, %s), sizeof(((%s *)0)->%s), %d },\n", indent, indent, field.Name, field.Type, cStruct.Name, field.Name, cStruct.Name, field.Name, field.Count))
+		}
+		w.WriteString(fmt.Sprintf("%s};\n\n", indent))
+		w.WriteString(fmt.Sprintf("%s*len = sizeof(fields) / sizeof(fields[0]);\n", indent))
+		w.WriteString(fmt.Sprintf("%sreturn fields;\n", indent))
+		w.WriteString("}\n")
+	default:
+		return fmt.Errorf("unknown field table %q, expected xmacro or table", c.Fields)
+	}
+
+	return nil
+}
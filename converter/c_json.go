@@ -0,0 +1,840 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CJSONConverter generates the c source implementing the X_from_json and
+// X_to_json functions declared by the c header when CConverter.JSON is set.
+// The functions are written against the cJSON library.
+type CJSONConverter struct {
+	CConverter
+	Header       string // the generated header to include
+	CJSONInclude string // how to include cJSON, defaults to "cJSON.h"
+}
+
+func (c *CJSONConverter) FileExtension() string {
+	return "c"
+}
+
+// BuildFiles generates only the .c file, the embedded CConverter would also
+// generate the header
+func (c *CJSONConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	return buildFile(c, name, inspecter)
+}
+
+const cJSONHelpers = `static inline char *gsc_json_strdup(const char *s, size_t n)
+{
+	char *d = (char *)malloc(n + 1);
+	if (d == NULL) {
+		return NULL;
+	}
+
+	memcpy(d, s, n);
+	d[n] = '\0';
+	return d;
+}
+
+static inline cJSON *gsc_json_string(const char *s)
+{
+	return cJSON_CreateString(s != NULL ? s : "");
+}
+
+static inline cJSON *gsc_json_string_n(const char *s, size_t size)
+{
+	size_t n = 0;
+	char *d;
+	cJSON *item;
+
+	while (n < size && s[n] != '\0') {
+		n++;
+	}
+
+	d = gsc_json_strdup(s, n);
+	if (d == NULL) {
+		return NULL;
+	}
+
+	item = cJSON_CreateString(d);
+	free(d);
+	return item;
+}
+
+static inline cJSON *gsc_json_int(int64_t v, int quoted)
+{
+	char buf[32];
+
+	if (!quoted) {
+		return cJSON_CreateNumber((double)v);
+	}
+
+	snprintf(buf, sizeof(buf), "%lld", (long long)v);
+	return cJSON_CreateString(buf);
+}
+
+static inline cJSON *gsc_json_uint(uint64_t v, int quoted)
+{
+	char buf[32];
+
+	if (!quoted) {
+		return cJSON_CreateNumber((double)v);
+	}
+
+	snprintf(buf, sizeof(buf), "%llu", (unsigned long long)v);
+	return cJSON_CreateString(buf);
+}
+
+static inline cJSON *gsc_json_float(double v, int quoted)
+{
+	char buf[32];
+
+	if (!quoted) {
+		return cJSON_CreateNumber(v);
+	}
+
+	snprintf(buf, sizeof(buf), "%.17g", v);
+	return cJSON_CreateString(buf);
+}
+
+static inline cJSON *gsc_json_bool(int v, int quoted)
+{
+	if (quoted) {
+		return cJSON_CreateString(v ? "true" : "false");
+	}
+
+	return cJSON_CreateBool(v);
+}
+
+static const char gsc_json_base64_chars[] = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+// go encodes []byte as a base64 string
+static inline cJSON *gsc_json_base64(const uint8_t *b, size_t n)
+{
+	size_t i, j = 0;
+	char *d = (char *)malloc((n + 2) / 3 * 4 + 1);
+	cJSON *item;
+
+	if (d == NULL) {
+		return NULL;
+	}
+
+	for (i = 0; i < n; i += 3) {
+		uint32_t v = (uint32_t)b[i] << 16;
+		if (i + 1 < n) {
+			v |= (uint32_t)b[i + 1] << 8;
+		}
+		if (i + 2 < n) {
+			v |= b[i + 2];
+		}
+
+		d[j++] = gsc_json_base64_chars[(v >> 18) & 63];
+		d[j++] = gsc_json_base64_chars[(v >> 12) & 63];
+		d[j++] = i + 1 < n ? gsc_json_base64_chars[(v >> 6) & 63] : '=';
+		d[j++] = i + 2 < n ? gsc_json_base64_chars[v & 63] : '=';
+	}
+	d[j] = '\0';
+
+	item = cJSON_CreateString(d);
+	free(d);
+	return item;
+}
+
+// a nil []byte is null
+static inline cJSON *gsc_json_bytes(const char *s)
+{
+	if (s == NULL) {
+		return cJSON_CreateNull();
+	}
+
+	return gsc_json_base64((const uint8_t *)s, strlen(s));
+}
+
+static inline int64_t gsc_json_days_from_civil(int64_t y, unsigned m, unsigned d)
+{
+	int64_t era;
+	unsigned yoe, doy, doe;
+
+	y -= m <= 2;
+	era = (y >= 0 ? y : y - 399) / 400;
+	yoe = (unsigned)(y - era * 400);
+	doy = (153 * (m > 2 ? m - 3 : m + 9) + 2) / 5 + d - 1;
+	doe = yoe * 365 + yoe / 4 - yoe / 100 + doy;
+	return era * 146097 + (int64_t)doe - 719468;
+}
+
+static inline void gsc_json_civil_from_days(int64_t z, int64_t *y, unsigned *m, unsigned *d)
+{
+	int64_t era;
+	unsigned doe, yoe, doy, mp;
+
+	z += 719468;
+	era = (z >= 0 ? z : z - 146096) / 146097;
+	doe = (unsigned)(z - era * 146097);
+	yoe = (doe - doe / 1460 + doe / 36524 - doe / 146096) / 365;
+	doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+	mp = (5 * doy + 2) / 153;
+	*d = doy - (153 * mp + 2) / 5 + 1;
+	*m = mp < 10 ? mp + 3 : mp - 9;
+	*y = (int64_t)yoe + era * 400 + (*m <= 2);
+}
+
+// go encodes time.Time as an RFC 3339 string, 0 is the zero time
+static inline cJSON *gsc_json_time(int64_t ns)
+{
+	char buf[64];
+	int64_t secs, days, rem, nsec, y;
+	unsigned m, d;
+	int n;
+
+	if (ns == 0) {
+		return cJSON_CreateString("0001-01-01T00:00:00Z");
+	}
+
+	secs = ns / 1000000000;
+	nsec = ns % 1000000000;
+	if (nsec < 0) {
+		nsec += 1000000000;
+		secs--;
+	}
+
+	days = secs / 86400;
+	rem = secs % 86400;
+	if (rem < 0) {
+		rem += 86400;
+		days--;
+	}
+
+	gsc_json_civil_from_days(days, &y, &m, &d);
+	n = snprintf(buf, sizeof(buf), "%04lld-%02u-%02uT%02d:%02d:%02d", (long long)y, m, d, (int)(rem / 3600), (int)(rem / 60 % 60), (int)(rem % 60));
+
+	if (nsec != 0) {
+		n += snprintf(buf + n, sizeof(buf) - n, ".%09lld", (long long)nsec);
+		while (buf[n - 1] == '0') {
+			n--;
+		}
+	}
+
+	buf[n++] = 'Z';
+	buf[n] = '\0';
+	return cJSON_CreateString(buf);
+}
+
+static inline int gsc_json_get_int(const cJSON *item, int quoted, int64_t *out)
+{
+	char *end;
+
+	if (quoted) {
+		if (!cJSON_IsString(item)) {
+			return -1;
+		}
+
+		*out = (int64_t)strtoll(item->valuestring, &end, 10);
+		return (*end != '\0' || end == item->valuestring) ? -1 : 0;
+	}
+
+	if (!cJSON_IsNumber(item)) {
+		return -1;
+	}
+
+	*out = (int64_t)item->valuedouble;
+	return 0;
+}
+
+static inline int gsc_json_get_uint(const cJSON *item, int quoted, uint64_t *out)
+{
+	char *end;
+
+	if (quoted) {
+		if (!cJSON_IsString(item)) {
+			return -1;
+		}
+
+		*out = (uint64_t)strtoull(item->valuestring, &end, 10);
+		return (*end != '\0' || end == item->valuestring) ? -1 : 0;
+	}
+
+	if (!cJSON_IsNumber(item) || item->valuedouble < 0) {
+		return -1;
+	}
+
+	*out = (uint64_t)item->valuedouble;
+	return 0;
+}
+
+static inline int gsc_json_get_float(const cJSON *item, int quoted, double *out)
+{
+	char *end;
+
+	if (quoted) {
+		if (!cJSON_IsString(item)) {
+			return -1;
+		}
+
+		*out = strtod(item->valuestring, &end);
+		return (*end != '\0' || end == item->valuestring) ? -1 : 0;
+	}
+
+	if (!cJSON_IsNumber(item)) {
+		return -1;
+	}
+
+	*out = item->valuedouble;
+	return 0;
+}
+
+static inline int gsc_json_get_bool(const cJSON *item, int quoted, int *out)
+{
+	if (quoted) {
+		if (!cJSON_IsString(item)) {
+			return -1;
+		}
+
+		if (strcmp(item->valuestring, "true") == 0) {
+			*out = 1;
+		} else if (strcmp(item->valuestring, "false") == 0) {
+			*out = 0;
+		} else {
+			return -1;
+		}
+
+		return 0;
+	}
+
+	if (!cJSON_IsBool(item)) {
+		return -1;
+	}
+
+	*out = cJSON_IsTrue(item) ? 1 : 0;
+	return 0;
+}
+
+static inline int gsc_json_get_string(const cJSON *item, char **out)
+{
+	if (!cJSON_IsString(item)) {
+		return -1;
+	}
+
+	*out = gsc_json_strdup(item->valuestring, strlen(item->valuestring));
+	return *out == NULL ? -1 : 0;
+}
+
+static inline int gsc_json_get_string_n(const cJSON *item, char *out, size_t size)
+{
+	size_t n;
+
+	if (!cJSON_IsString(item)) {
+		return -1;
+	}
+
+	n = strlen(item->valuestring);
+	if (n >= size) {
+		return -1;
+	}
+
+	memcpy(out, item->valuestring, n + 1);
+	return 0;
+}
+
+// decodes at most size bytes of base64 into out
+static inline int gsc_json_get_base64(const cJSON *item, uint8_t *out, size_t size, size_t *n)
+{
+	const char *s;
+	uint32_t v = 0;
+	int bits = 0;
+
+	if (!cJSON_IsString(item)) {
+		return -1;
+	}
+
+	*n = 0;
+	for (s = item->valuestring; *s != '\0' && *s != '='; s++) {
+		const char *p = strchr(gsc_json_base64_chars, *s);
+		if (p == NULL) {
+			return -1;
+		}
+
+		v = (v << 6) | (uint32_t)(p - gsc_json_base64_chars);
+		bits += 6;
+		if (bits >= 8) {
+			bits -= 8;
+			if (*n >= size) {
+				return -1;
+			}
+			out[(*n)++] = (uint8_t)((v >> bits) & 0xff);
+		}
+	}
+
+	return 0;
+}
+
+// allocates size + 1 bytes, enough for the decoded base64 and a terminator
+static inline int gsc_json_alloc_base64(const cJSON *item, uint8_t **out, size_t *size)
+{
+	if (!cJSON_IsString(item)) {
+		return -1;
+	}
+
+	*size = strlen(item->valuestring) / 4 * 3 + 3;
+	*out = (uint8_t *)malloc(*size + 1);
+	return *out == NULL ? -1 : 0;
+}
+
+static inline int gsc_json_get_bytes(const cJSON *item, char **out)
+{
+	uint8_t *d;
+	size_t size, n;
+
+	if (gsc_json_alloc_base64(item, &d, &size) != 0) {
+		return -1;
+	}
+
+	if (gsc_json_get_base64(item, d, size, &n) != 0) {
+		free(d);
+		return -1;
+	}
+
+	d[n] = '\0';
+	*out = (char *)d;
+	return 0;
+}
+
+static inline int gsc_json_get_byte_slice(const cJSON *item, uint8_t **out, size_t *n)
+{
+	size_t size;
+
+	if (gsc_json_alloc_base64(item, out, &size) != 0) {
+		return -1;
+	}
+
+	if (gsc_json_get_base64(item, *out, size, n) != 0) {
+		free(*out);
+		*out = NULL;
+		return -1;
+	}
+
+	return 0;
+}
+
+static inline int gsc_json_get_time(const cJSON *item, int64_t *out)
+{
+	const char *s;
+	int year, mon, day, hour, min, sec, n = 0, digits = 0;
+	int64_t frac = 0, offset = 0;
+
+	if (!cJSON_IsString(item)) {
+		return -1;
+	}
+
+	s = item->valuestring;
+	if (sscanf(s, "%4d-%2d-%2dT%2d:%2d:%2d%n", &year, &mon, &day, &hour, &min, &sec, &n) != 6 || n != 19) {
+		return -1;
+	}
+	s += n;
+
+	if (*s == '.') {
+		s++;
+		while (*s >= '0' && *s <= '9') {
+			if (digits < 9) {
+				frac = frac * 10 + (*s - '0');
+				digits++;
+			}
+			s++;
+		}
+
+		if (digits == 0) {
+			return -1;
+		}
+
+		for (; digits < 9; digits++) {
+			frac *= 10;
+		}
+	}
+
+	if (*s == 'Z' || *s == 'z') {
+		s++;
+	} else if (*s == '+' || *s == '-') {
+		int oh, om;
+		int sign = *s == '-' ? -1 : 1;
+
+		if (sscanf(s + 1, "%2d:%2d%n", &oh, &om, &n) != 2 || n != 5) {
+			return -1;
+		}
+
+		offset = sign * (oh * 3600 + om * 60);
+		s += 6;
+	} else {
+		return -1;
+	}
+
+	if (*s != '\0') {
+		return -1;
+	}
+
+	if (year == 1 && mon == 1 && day == 1 && hour == 0 && min == 0 && sec == 0 && frac == 0 && offset == 0) {
+		*out = 0;
+		return 0;
+	}
+
+	*out = ((gsc_json_days_from_civil(year, (unsigned)mon, (unsigned)day) * 86400 + hour * 3600 + min * 60 + sec - offset) * 1000000000) + frac;
+	return 0;
+}
+
+`
+
+// quoted is whether the ",string" option applies to the numbers and bools of a member
+func (c *CJSONConverter) quoted(member StructMember) string {
+	if member.JSONString {
+		return "1"
+	}
+
+	return "0"
+}
+
+// encodeValue writes the statements creating the cJSON item for expr and
+// attaching it with attach, a format string taking the item
+func (c *CJSONConverter) encodeValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, vt *ValueType, quoted string, attach string, depth int) {
+	item := fmt.Sprintf("v%d", depth)
+	create := ""
+
+	switch vt.Kind {
+	case KindBool:
+		create = fmt.Sprintf("gsc_json_bool(%s, %s)", expr, quoted)
+	case KindInt:
+		create = fmt.Sprintf("gsc_json_int((int64_t)%s, %s)", expr, quoted)
+	case KindUint:
+		create = fmt.Sprintf("gsc_json_uint((uint64_t)%s, %s)", expr, quoted)
+	case KindFloat:
+		create = fmt.Sprintf("gsc_json_float((double)%s, %s)", expr, quoted)
+	case KindString:
+		if vt.GoType == "[]byte" {
+			create = fmt.Sprintf("gsc_json_bytes(%s)", expr)
+		} else if vt.Fixed > 0 {
+			create = fmt.Sprintf("gsc_json_string_n(%s, %d)", expr, vt.Fixed)
+		} else {
+			create = fmt.Sprintf("gsc_json_string(%s)", expr)
+		}
+	case KindTime:
+		create = fmt.Sprintf("gsc_json_time((int64_t)%s)", expr)
+	case KindStruct:
+		create = fmt.Sprintf("%s_to_json(&%s)", vt.Struct.Name, expr)
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", indent, expr))
+		c.attachItem(w, indent+inspecter.Indent, inspecter, item, "cJSON_CreateNull()", attach)
+		w.WriteString(fmt.Sprintf("%s} else {\n", indent))
+		c.encodeValue(w, indent+inspecter.Indent, inspecter, fmt.Sprintf("(*%s)", expr), vt.Elem, quoted, attach, depth)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+		return
+	case KindSlice, KindMap:
+		if isByteSlice(vt) {
+			if vt.Fixed > 0 {
+				create = fmt.Sprintf("gsc_json_base64(%s, %d)", expr, vt.Fixed)
+			} else {
+				create = fmt.Sprintf("%s == NULL ? cJSON_CreateNull() : gsc_json_base64(%s, %s_len)", expr, expr, expr)
+			}
+			break
+		}
+
+		inner := indent + inspecter.Indent
+		index := fmt.Sprintf("i%d", depth)
+		count := expr + "_len"
+		if vt.Fixed > 0 {
+			count = fmt.Sprintf("%d", vt.Fixed)
+		}
+
+		if vt.Fixed == 0 {
+			// nil slices and maps are null in go
+			w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", indent, expr))
+			c.attachItem(w, inner, inspecter, item, "cJSON_CreateNull()", attach)
+			w.WriteString(fmt.Sprintf("%s} else {\n", indent))
+		} else {
+			w.WriteString(fmt.Sprintf("%s{\n", indent))
+		}
+
+		if vt.Kind == KindSlice {
+			c.attachItem(w, inner, inspecter, item, "cJSON_CreateArray()", attach)
+			w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", inner, index, index, count, index))
+			c.encodeValue(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s]", expr, index), vt.Elem, quoted, fmt.Sprintf("cJSON_AddItemToArray(%s, %%s);", item), depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", inner))
+		} else {
+			c.attachItem(w, inner, inspecter, item, "cJSON_CreateObject()", attach)
+			w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", inner, index, index, count, index))
+			key := fmt.Sprintf("k%d", depth)
+			entry := fmt.Sprintf("%s[%s]", expr, index)
+			switch vt.Key.Kind {
+			case KindString:
+				w.WriteString(fmt.Sprintf("%s%sconst char *%s = %s.key != NULL ? %s.key : \"\";\n", inner, inspecter.Indent, key, entry, entry))
+			case KindInt:
+				w.WriteString(fmt.Sprintf("%s%schar %s[32];\n", inner, inspecter.Indent, key))
+				w.WriteString(fmt.Sprintf("%s%ssnprintf(%s, sizeof(%s), \"%%lld\", (long long)%s.key);\n", inner, inspecter.Indent, key, key, entry))
+			case KindUint:
+				w.WriteString(fmt.Sprintf("%s%schar %s[32];\n", inner, inspecter.Indent, key))
+				w.WriteString(fmt.Sprintf("%s%ssnprintf(%s, sizeof(%s), \"%%llu\", (unsigned long long)%s.key);\n", inner, inspecter.Indent, key, key, entry))
+			}
+			c.encodeValue(w, inner+inspecter.Indent, inspecter, entry+".value", vt.Elem, quoted, fmt.Sprintf("cJSON_AddItemToObject(%s, %s, %%s);", item, key), depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", inner))
+		}
+
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+		return
+	}
+
+	c.attachItem(w, indent, inspecter, item, create, attach)
+}
+
+// attachItem creates an item and attaches it straight away so a failure only needs to delete the root object
+func (c *CJSONConverter) attachItem(w *strings.Builder, indent string, inspecter *Inspecter, item string, create string, attach string) {
+	w.WriteString(fmt.Sprintf("%scJSON *%s = %s;\n", indent, item, create))
+	w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", indent, item))
+	w.WriteString(fmt.Sprintf("%s%sgoto fail;\n", indent, inspecter.Indent))
+	w.WriteString(fmt.Sprintf("%s}\n", indent))
+	w.WriteString(fmt.Sprintf("%s%s\n", indent, fmt.Sprintf(attach, item)))
+}
+
+// decodeValue writes the statements reading the cJSON item into expr
+func (c *CJSONConverter) decodeValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, vt *ValueType, quoted string, item string, depth int) {
+	inner := indent + inspecter.Indent
+	fail := fmt.Sprintf("%sreturn -1;\n%s}\n", inner, indent)
+
+	scalar := func(cType string, getter string) {
+		w.WriteString(fmt.Sprintf("%s{\n", indent))
+		w.WriteString(fmt.Sprintf("%s%s v;\n", inner, cType))
+		w.WriteString(fmt.Sprintf("%sif (%s(%s, %s, &v) != 0) {\n", inner, getter, item, quoted))
+		w.WriteString(fmt.Sprintf("%s%sreturn -1;\n%s}\n", inner, inspecter.Indent, inner))
+		w.WriteString(fmt.Sprintf("%s%s = v;\n", inner, expr))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		scalar("int", "gsc_json_get_bool")
+	case KindInt:
+		scalar("int64_t", "gsc_json_get_int")
+	case KindUint:
+		scalar("uint64_t", "gsc_json_get_uint")
+	case KindFloat:
+		scalar("double", "gsc_json_get_float")
+	case KindString:
+		if vt.GoType == "[]byte" {
+			w.WriteString(fmt.Sprintf("%sif (gsc_json_get_bytes(%s, &%s) != 0) {\n", indent, item, expr))
+		} else if vt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%sif (gsc_json_get_string_n(%s, %s, %d) != 0) {\n", indent, item, expr, vt.Fixed))
+		} else {
+			w.WriteString(fmt.Sprintf("%sif (gsc_json_get_string(%s, &%s) != 0) {\n", indent, item, expr))
+		}
+		w.WriteString(fail)
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%sif (gsc_json_get_time(%s, &%s) != 0) {\n", indent, item, expr))
+		w.WriteString(fail)
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%sif (%s_from_json(%s, &%s) != 0) {\n", indent, vt.Struct.Name, item, expr))
+		w.WriteString(fail)
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%s%s = calloc(1, sizeof(*%s));\n", indent, expr, expr))
+		w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n", indent, expr))
+		w.WriteString(fail)
+		c.decodeValue(w, indent, inspecter, fmt.Sprintf("(*%s)", expr), vt.Elem, quoted, item, depth)
+	case KindSlice, KindMap:
+		if isByteSlice(vt) {
+			if vt.Fixed > 0 {
+				w.WriteString(fmt.Sprintf("%s{\n", indent))
+				w.WriteString(fmt.Sprintf("%ssize_t n;\n", inner))
+				w.WriteString(fmt.Sprintf("%sif (gsc_json_get_base64(%s, %s, %d, &n) != 0) {\n", inner, item, expr, vt.Fixed))
+				w.WriteString(fmt.Sprintf("%s%sreturn -1;\n%s}\n", inner, inspecter.Indent, inner))
+				w.WriteString(fmt.Sprintf("%s}\n", indent))
+			} else {
+				w.WriteString(fmt.Sprintf("%sif (gsc_json_get_byte_slice(%s, &%s, &%s_len) != 0) {\n", indent, item, expr, expr))
+				w.WriteString(fail)
+			}
+			return
+		}
+
+		count := fmt.Sprintf("n%d", depth)
+		index := fmt.Sprintf("i%d", depth)
+		elem := fmt.Sprintf("e%d", depth)
+
+		check := "cJSON_IsArray"
+		if vt.Kind == KindMap {
+			check = "cJSON_IsObject"
+		}
+
+		w.WriteString(fmt.Sprintf("%s{\n", indent))
+		w.WriteString(fmt.Sprintf("%sconst cJSON *%s;\n", inner, elem))
+		w.WriteString(fmt.Sprintf("%ssize_t %s = 0;\n", inner, index))
+		w.WriteString(fmt.Sprintf("%ssize_t %s;\n", inner, count))
+		w.WriteString(fmt.Sprintf("%sif (!%s(%s)) {\n", inner, check, item))
+		w.WriteString(fmt.Sprintf("%s%sreturn -1;\n%s}\n", inner, inspecter.Indent, inner))
+		w.WriteString(fmt.Sprintf("%s%s = (size_t)cJSON_GetArraySize(%s);\n", inner, count, item))
+
+		if vt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%sif (%s > %d) {\n", inner, count, vt.Fixed))
+			w.WriteString(fmt.Sprintf("%s%sreturn -1;\n%s}\n", inner, inspecter.Indent, inner))
+		} else {
+			w.WriteString(fmt.Sprintf("%sif (%s > 0) {\n", inner, count))
+			w.WriteString(fmt.Sprintf("%s%s%s = calloc(%s, sizeof(*%s));\n", inner, inspecter.Indent, expr, count, expr))
+			w.WriteString(fmt.Sprintf("%s%sif (%s == NULL) {\n", inner, inspecter.Indent, expr))
+			w.WriteString(fmt.Sprintf("%s%s%sreturn -1;\n%s%s}\n", inner, inspecter.Indent, inspecter.Indent, inner, inspecter.Indent))
+			w.WriteString(fmt.Sprintf("%s%s%s_len = %s;\n", inner, inspecter.Indent, expr, count))
+			w.WriteString(fmt.Sprintf("%s}\n", inner))
+		}
+
+		loop := inner + inspecter.Indent
+		target := fmt.Sprintf("%s[%s]", expr, index)
+		w.WriteString(fmt.Sprintf("%scJSON_ArrayForEach(%s, %s) {\n", inner, elem, item))
+
+		if vt.Kind == KindMap {
+			switch vt.Key.Kind {
+			case KindString:
+				w.WriteString(fmt.Sprintf("%s%s.key = gsc_json_strdup(%s->string, strlen(%s->string));\n", loop, target, elem, elem))
+				w.WriteString(fmt.Sprintf("%sif (%s.key == NULL) {\n", loop, target))
+				w.WriteString(fmt.Sprintf("%s%sreturn -1;\n%s}\n", loop, inspecter.Indent, loop))
+			case KindInt, KindUint:
+				parse := "strtoll"
+				if vt.Key.Kind == KindUint {
+					parse = "strtoull"
+				}
+				w.WriteString(fmt.Sprintf("%s{\n", loop))
+				w.WriteString(fmt.Sprintf("%s%schar *end;\n", loop, inspecter.Indent))
+				w.WriteString(fmt.Sprintf("%s%s%s.key = %s(%s->string, &end, 10);\n", loop, inspecter.Indent, target, parse, elem))
+				w.WriteString(fmt.Sprintf("%s%sif (*end != '\\0' || end == %s->string) {\n", loop, inspecter.Indent, elem))
+				w.WriteString(fmt.Sprintf("%s%s%sreturn -1;\n%s%s}\n", loop, inspecter.Indent, inspecter.Indent, loop, inspecter.Indent))
+				w.WriteString(fmt.Sprintf("%s}\n", loop))
+			}
+			target += ".value"
+		}
+
+		w.WriteString(fmt.Sprintf("%sif (!cJSON_IsNull(%s)) {\n", loop, elem))
+		c.decodeValue(w, loop+inspecter.Indent, inspecter, target, vt.Elem, quoted, elem, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", loop))
+		w.WriteString(fmt.Sprintf("%s%s++;\n", loop, index))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+// isByteSlice is whether encoding/json treats a slice as []byte and base64 encodes it
+func isByteSlice(vt *ValueType) bool {
+	return vt.Kind == KindSlice && vt.Elem.Kind == KindUint && vt.Elem.Size == 1
+}
+
+// omitEmpty returns the condition under which encoding/json omits an omitempty member, "" if it never does
+func (c *CJSONConverter) omitEmpty(expr string, vt *ValueType) string {
+	switch vt.Kind {
+	case KindBool:
+		return fmt.Sprintf("!%s", expr)
+	case KindInt, KindUint, KindFloat:
+		return fmt.Sprintf("%s == 0", expr)
+	case KindString:
+		if vt.Fixed > 0 {
+			return fmt.Sprintf("%s[0] == '\\0'", expr)
+		}
+		return fmt.Sprintf("(%s == NULL || %s[0] == '\\0')", expr, expr)
+	case KindPointer:
+		return fmt.Sprintf("%s == NULL", expr)
+	case KindSlice, KindMap:
+		if vt.Fixed > 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s_len == 0", expr)
+	}
+
+	return ""
+}
+
+func (c *CJSONConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	w.WriteString(cBanner(inspecter))
+	w.WriteString(cIgnoreDeprecations(inspecter))
+
+	if c.Header != "" {
+		w.WriteString(fmt.Sprintf("#include \"%s\"\n", c.Header))
+	}
+
+	include := c.CJSONInclude
+	if include == "" {
+		include = "\"cJSON.h\""
+	}
+	w.WriteString(fmt.Sprintf("#include %s\n\n", CleanCInclude(include)))
+
+	w.WriteString("#include <stdint.h>\n")
+	w.WriteString("#include <stdio.h>\n")
+	w.WriteString("#include <stdlib.h>\n")
+	w.WriteString("#include <string.h>\n\n")
+
+	w.WriteString(cJSONHelpers)
+
+	for _, cStruct := range inspecter.Structs {
+		var members []StructMember
+		var types []*ValueType
+
+		for _, member := range cStruct.Members {
+			if member.JSONIgnore {
+				continue
+			}
+
+			vt, err := inspecter.MemberValueType(member)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING! %s, skipping %s.%s\n", err, cStruct.Name, member.Name)
+				continue
+			}
+
+			members = append(members, member)
+			types = append(types, vt)
+		}
+
+		body := new(strings.Builder)
+		for i, member := range members {
+			indent := inspecter.Indent
+			expr := fmt.Sprintf("in->%s", member.Name)
+			attach := fmt.Sprintf("cJSON_AddItemToObject(json, %q, %%s);", member.JSONName)
+
+			condition := ""
+			if member.JSONOmitEmpty {
+				condition = c.omitEmpty(expr, types[i])
+			}
+
+			if condition != "" {
+				body.WriteString(fmt.Sprintf("%sif (!(%s)) {\n", indent, condition))
+			} else {
+				body.WriteString(fmt.Sprintf("%s{\n", indent))
+			}
+			indent += inspecter.Indent
+
+			c.encodeValue(body, indent, inspecter, expr, types[i], c.quoted(member), attach, 0)
+			body.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+		}
+
+		w.WriteString(fmt.Sprintf("cJSON *%s_to_json(const %s *in)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%scJSON *json = cJSON_CreateObject();\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sif (json == NULL) {\n%s%sreturn NULL;\n%s}\n\n", inspecter.Indent, inspecter.Indent, inspecter.Indent, inspecter.Indent))
+		if len(members) == 0 {
+			w.WriteString(fmt.Sprintf("%s(void)in;\n", inspecter.Indent))
+		}
+		w.WriteString(body.String())
+		w.WriteString(fmt.Sprintf("\n%sreturn json;\n", inspecter.Indent))
+		if strings.Contains(body.String(), "goto fail;") {
+			w.WriteString(fmt.Sprintf("\nfail:\n%scJSON_Delete(json);\n%sreturn NULL;\n", inspecter.Indent, inspecter.Indent))
+		}
+		w.WriteString("}\n\n")
+
+		// cJSON_GetObjectItem matches names case insensitively like encoding/json
+		w.WriteString(fmt.Sprintf("int %s_from_json(const cJSON *json, %s *out)\n{\n", cStruct.Name, cStruct.Name))
+		if len(members) > 0 {
+			w.WriteString(fmt.Sprintf("%sconst cJSON *item;\n\n", inspecter.Indent))
+		}
+		w.WriteString(fmt.Sprintf("%smemset(out, 0, sizeof(*out));\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sif (!cJSON_IsObject(json)) {\n%s%sreturn -1;\n%s}\n", inspecter.Indent, inspecter.Indent, inspecter.Indent, inspecter.Indent))
+
+		for i, member := range members {
+			indent := inspecter.Indent + inspecter.Indent
+			w.WriteString(fmt.Sprintf("\n%sitem = cJSON_GetObjectItem(json, %q);\n", inspecter.Indent, member.JSONName))
+			w.WriteString(fmt.Sprintf("%sif (item != NULL && !cJSON_IsNull(item)) {\n", inspecter.Indent))
+			c.decodeValue(w, indent, inspecter, fmt.Sprintf("out->%s", member.Name), types[i], c.quoted(member), "item", 0)
+			w.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+		}
+
+		w.WriteString(fmt.Sprintf("\n%sreturn 0;\n}\n\n", inspecter.Indent))
+	}
+
+	return nil
+}
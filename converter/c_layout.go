@@ -0,0 +1,311 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CMemberLayout is where a single struct member is expected to live in memory
+type CMemberLayout struct {
+	Name      string
+	Offset    int
+	Size      int
+	Align     int
+	Bits      int // the width of a bitfield, 0 for other members
+	BitOffset int // the first bit of a bitfield within the byte at Offset
+}
+
+// CStructLayout is the memory layout a C compiler is expected to give a struct.
+// Sizes assume natural alignment of scalars (as on x86_64 and aarch64).
+type CStructLayout struct {
+	Name    string
+	Size    int
+	Align   int
+	Members []CMemberLayout
+}
+
+var cScalarSizes = map[string]int{
+	"char":               1,
+	"signed char":        1,
+	"unsigned char":      1,
+	"bool":               1,
+	"_Bool":              1,
+	"int8_t":             1,
+	"uint8_t":            1,
+	"short":              2,
+	"unsigned short":     2,
+	"int16_t":            2,
+	"uint16_t":           2,
+	"int":                4,
+	"unsigned int":       4,
+	"int32_t":            4,
+	"uint32_t":           4,
+	"float":              4,
+	"long long":          8,
+	"unsigned long long": 8,
+	"int64_t":            8,
+	"uint64_t":           8,
+	"double":             8,
+}
+
+var cArraySuffixRe = regexp.MustCompile(`\[[\s]*([0-9]+)[\s]*\]`)
+
+// cArrayCount returns the number of elements declared by a suffix such as `[255]` or `[2][3]`
+func cArrayCount(suffix string) (int, error) {
+	count := 1
+	for _, match := range cArraySuffixRe.FindAllStringSubmatch(suffix, -1) {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		count *= n
+	}
+
+	return count, nil
+}
+
+func (c *CConverter) pointerSize() int {
+	if c.PointerSize > 0 {
+		return c.PointerSize
+	}
+
+	return 8
+}
+
+// boolSize is the size of the bool_t typedef go bools are declared with
+func (c *CConverter) boolSize() int {
+	if c.BoolSize > 0 {
+		return c.BoolSize
+	}
+
+	return 1
+}
+
+func (c *CConverter) memberSize(member StructMember, layouts map[string]CStructLayout) (int, int, error) {
+	base := strings.TrimSpace(member.Type.Prefix + member.Type.Value)
+
+	if len(member.OneOf) > 0 {
+		return c.oneOfSize(member, layouts)
+	}
+
+	var size, align int
+	if member.Type.IsPointer || member.Type.IsArray || member.Type.IsMap || strings.HasSuffix(base, "*") {
+		size = c.pointerSize()
+		align = size
+	} else if base == c.GetIdent("bool") {
+		size = c.boolSize()
+		align = size
+	} else if s, ok := cScalarSizes[base]; ok {
+		size = s
+		align = s
+	} else if nested, ok := layouts[base]; ok {
+		size = nested.Size
+		align = nested.Align
+	} else {
+		return 0, 0, fmt.Errorf("unknown size for %s (%s)", member.Name, base)
+	}
+
+	count, err := cArrayCount(member.Type.Suffix)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return size * count, align, nil
+}
+
+// oneOfSize is the size of a tagged union, an enum followed by a union of the variants
+func (c *CConverter) oneOfSize(member StructMember, layouts map[string]CStructLayout) (int, int, error) {
+	kindSize := cScalarSizes["int"]
+
+	size, align := 0, kindSize
+	for _, variant := range member.OneOf {
+		nested, ok := layouts[variant.Type]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown size for %s (%s)", member.Name, variant.Type)
+		}
+
+		if nested.Size > size {
+			size = nested.Size
+		}
+		if nested.Align > align {
+			align = nested.Align
+		}
+	}
+
+	return alignTo(alignTo(kindSize, align)+size, align), align, nil
+}
+
+// Layout computes the expected memory layout for every struct, honouring the
+// packing and alignment directives. Structs whose layout cannot be determined
+// (because a member type is unknown) are left out of the result.
+func (c *CConverter) Layout(inspecter *Inspecter) (map[string]CStructLayout, error) {
+	layouts := make(map[string]CStructLayout)
+
+	// nested structs can be declared after the struct using them, so keep
+	// resolving until nothing changes
+	for progress := true; progress; {
+		progress = false
+
+		for _, cStruct := range inspecter.Structs {
+			if _, done := layouts[cStruct.Name]; done {
+				continue
+			}
+
+			layout, err := c.structLayout(cStruct, layouts)
+			if err != nil {
+				if _, ok := err.(*cLayoutUnknownError); ok {
+					continue
+				}
+				return nil, err
+			}
+
+			layouts[cStruct.Name] = layout
+			progress = true
+		}
+	}
+
+	return layouts, nil
+}
+
+type cLayoutUnknownError struct {
+	err error
+}
+
+func (e *cLayoutUnknownError) Error() string {
+	return e.err.Error()
+}
+
+func (c *CConverter) structLayout(cStruct Struct, layouts map[string]CStructLayout) (CStructLayout, error) {
+	attrs, err := c.structAttributes(cStruct)
+	if err != nil {
+		return CStructLayout{}, err
+	}
+
+	layout := CStructLayout{
+		Name:  cStruct.Name,
+		Align: 1,
+	}
+
+	// the offset is tracked in bits so bitfields can share storage units
+	offsetBits := 0
+	for _, member := range cStruct.Members {
+		if member.Type.IsMap && !c.supportedMap(member) {
+			continue
+		}
+
+		size, align, err := c.memberSize(member, layouts)
+		if err != nil {
+			return layout, &cLayoutUnknownError{err}
+		}
+
+		bits, err := memberBits(member)
+		if err != nil {
+			return layout, err
+		}
+
+		if bits > 0 {
+			if attrs.Packed || attrs.Pack > 0 {
+				return layout, &cLayoutUnknownError{fmt.Errorf("%s: bitfields in packed structs are compiler specific", member.Name)}
+			}
+
+			// a bitfield moves to the next storage unit of its type rather than straddle two
+			unit := size * 8
+			if offsetBits/unit != (offsetBits+bits-1)/unit {
+				offsetBits = alignTo(offsetBits, unit)
+			}
+
+			layout.Members = append(layout.Members, CMemberLayout{
+				Name:      member.Name,
+				Offset:    offsetBits / 8,
+				Size:      size,
+				Align:     align,
+				Bits:      bits,
+				BitOffset: offsetBits % 8,
+			})
+
+			offsetBits += bits
+			if align > layout.Align {
+				layout.Align = align
+			}
+			continue
+		}
+
+		offset := (offsetBits + 7) / 8
+
+		explicit, err := memberAlignment(member)
+		if err != nil {
+			return layout, err
+		}
+
+		if attrs.Packed {
+			align = 1
+		}
+
+		if explicit > align {
+			align = explicit
+		}
+
+		if attrs.Pack > 0 && align > attrs.Pack {
+			align = attrs.Pack
+		}
+
+		offset = alignTo(offset, align)
+
+		layout.Members = append(layout.Members, CMemberLayout{
+			Name:   member.Name,
+			Offset: offset,
+			Size:   size,
+			Align:  align,
+		})
+
+		offset += size
+		if align > layout.Align {
+			layout.Align = align
+		}
+
+		if member.Type.IsArray || member.Type.IsMap {
+			// the element count that follows every slice and map
+			size = c.pointerSize()
+			align = size
+			if attrs.Packed {
+				align = 1
+			}
+			if attrs.Pack > 0 && align > attrs.Pack {
+				align = attrs.Pack
+			}
+
+			offset = alignTo(offset, align)
+			layout.Members = append(layout.Members, CMemberLayout{
+				Name:   member.Name + "_len",
+				Offset: offset,
+				Size:   size,
+				Align:  align,
+			})
+
+			offset += size
+			if align > layout.Align {
+				layout.Align = align
+			}
+		}
+
+		offsetBits = offset * 8
+	}
+
+	if attrs.Align > layout.Align {
+		layout.Align = attrs.Align
+	}
+
+	layout.Size = alignTo((offsetBits+7)/8, layout.Align)
+
+	return layout, nil
+}
+
+func alignTo(offset int, align int) int {
+	if align <= 1 {
+		return offset
+	}
+
+	return (offset + align - 1) / align * align
+}
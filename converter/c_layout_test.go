@@ -0,0 +1,93 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCLayoutOfBoolMembers(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Flags struct {
+	Count  int32
+	Active bool
+	Mode   uint8
+}
+`)
+
+	for _, tc := range []struct {
+		boolSize int
+		asserts  []string
+	}{
+		{0, []string{
+			`static_assert(sizeof(Flags) == 8, "unexpected size for Flags");`,
+			`static_assert(offsetof(Flags, Active) == 4, "unexpected offset for Flags.Active");`,
+			`static_assert(offsetof(Flags, Mode) == 5, "unexpected offset for Flags.Mode");`,
+		}},
+		{4, []string{
+			`static_assert(sizeof(Flags) == 12, "unexpected size for Flags");`,
+			`static_assert(offsetof(Flags, Mode) == 8, "unexpected offset for Flags.Mode");`,
+		}},
+	} {
+		inspecter := &Inspecter{Converter: &CConverter{StaticAsserts: true, BoolSize: tc.boolSize}, Indent: "\t"}
+		files, err := inspecter.ConvertToFiles([]string{input}, "Flags")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, assert := range tc.asserts {
+			if !strings.Contains(files[0].Content, assert) {
+				t.Errorf("bool size %d: missing %s in:\n%s", tc.boolSize, assert, files[0].Content)
+			}
+		}
+	}
+}
+
+func TestCLayoutOfBoolBitfields(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Status struct {
+	Ready bool  `+"`cbits:\"1\"`"+`
+	Mode  uint8 `+"`cbits:\"3\"`"+`
+	Count int32
+}
+`)
+
+	c := &CConverter{}
+	inspecter := &Inspecter{Converter: c, Indent: "\t"}
+	asts, err := inspecter.parseFiles([]string{input})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inspecter.prepare(asts); err != nil {
+		t.Fatal(err)
+	}
+
+	layouts, err := c.Layout(inspecter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layout, ok := layouts["Status"]
+	if !ok {
+		t.Fatal("the layout of Status is unknown")
+	}
+
+	if layout.Size != 8 || layout.Align != 4 {
+		t.Errorf("expected size 8 and align 4, got %d and %d", layout.Size, layout.Align)
+	}
+
+	expected := []CMemberLayout{
+		{Name: "Ready", Offset: 0, Size: 1, Align: 1, Bits: 1, BitOffset: 0},
+		{Name: "Mode", Offset: 0, Size: 1, Align: 1, Bits: 3, BitOffset: 1},
+		{Name: "Count", Offset: 4, Size: 4, Align: 4},
+	}
+	if len(layout.Members) != len(expected) {
+		t.Fatalf("expected %d members, got %+v", len(expected), layout.Members)
+	}
+	for i, member := range expected {
+		if layout.Members[i] != member {
+			t.Errorf("expected %+v, got %+v", member, layout.Members[i])
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CLifecycleConverter generates the c source implementing the X_init, X_free,
+// X_copy and X_equal functions declared by the c header when
+// CConverter.Lifecycle is set. Strings, pointers, slices and maps are owned
+// by the struct, so X_free and X_copy recurse into them and into nested
+// structs.
+type CLifecycleConverter struct {
+	CConverter
+	Header string // the generated header to include
+}
+
+func (c *CLifecycleConverter) FileExtension() string {
+	return "c"
+}
+
+// BuildFiles generates only the .c file, the embedded CConverter would also
+// generate the header
+func (c *CLifecycleConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	return buildFile(c, name, inspecter)
+}
+
+const cLifecycleHelpers = `static inline char *gsc_strdup(const char *s)
+{
+	size_t n = strlen(s);
+	char *d = (char *)malloc(n + 1);
+	if (d != NULL) {
+		memcpy(d, s, n + 1);
+	}
+
+	return d;
+}
+
+static inline int gsc_str_equal(const char *a, const char *b)
+{
+	if (a == NULL || b == NULL) {
+		return a == b;
+	}
+
+	return strcmp(a, b) == 0;
+}
+
+`
+
+// cNeedsFree is whether a value owns memory that X_free has to release
+func cNeedsFree(vt *ValueType) bool {
+	switch vt.Kind {
+	case KindString:
+		return vt.Fixed == 0
+	case KindPointer, KindMap, KindStruct:
+		return true
+	case KindSlice:
+		return vt.Fixed == 0 || cNeedsFree(vt.Elem)
+	}
+
+	return false
+}
+
+// freeValue writes the statements releasing the memory owned by expr
+func (c *CLifecycleConverter) freeValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, vt *ValueType, depth int) {
+	inner := indent + inspecter.Indent
+
+	switch vt.Kind {
+	case KindString:
+		if vt.Fixed == 0 {
+			w.WriteString(fmt.Sprintf("%sfree(%s);\n", indent, expr))
+		}
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%s%s_free(&%s);\n", indent, vt.Struct.Name, expr))
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sif (%s != NULL) {\n", indent, expr))
+		if cNeedsFree(vt.Elem) {
+			c.freeValue(w, inner, inspecter, fmt.Sprintf("(*%s)", expr), vt.Elem, depth)
+		}
+		w.WriteString(fmt.Sprintf("%sfree(%s);\n", inner, expr))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice, KindMap:
+		index := fmt.Sprintf("i%d", depth)
+		count := expr + "_len"
+		if vt.Fixed > 0 {
+			count = fmt.Sprintf("%d", vt.Fixed)
+		}
+
+		elemNeedsFree := cNeedsFree(vt.Elem)
+		keyNeedsFree := vt.Kind == KindMap && cNeedsFree(vt.Key)
+		if elemNeedsFree || keyNeedsFree {
+			w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", indent, index, index, count, index))
+			elem := fmt.Sprintf("%s[%s]", expr, index)
+			if vt.Kind == KindMap {
+				if keyNeedsFree {
+					c.freeValue(w, inner, inspecter, elem+".key", vt.Key, depth+1)
+				}
+				elem += ".value"
+			}
+			if elemNeedsFree {
+				c.freeValue(w, inner, inspecter, elem, vt.Elem, depth+1)
+			}
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+		}
+
+		if vt.Fixed == 0 {
+			w.WriteString(fmt.Sprintf("%sfree(%s);\n", indent, expr))
+		}
+	}
+}
+
+// copyValue writes the statements deep copying src into the zeroed dst
+func (c *CLifecycleConverter) copyValue(w *strings.Builder, indent string, inspecter *Inspecter, dst string, src string, vt *ValueType, depth int) {
+	inner := indent + inspecter.Indent
+
+	switch vt.Kind {
+	case KindBool, KindInt, KindUint, KindFloat, KindTime:
+		w.WriteString(fmt.Sprintf("%s%s = %s;\n", indent, dst, src))
+	case KindString:
+		if vt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%smemcpy(%s, %s, sizeof(%s));\n", indent, dst, src, dst))
+			return
+		}
+		w.WriteString(fmt.Sprintf("%sif (%s != NULL) {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s = gsc_strdup(%s);\n", inner, dst, src))
+		w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n%s%sgoto fail;\n%s}\n", inner, dst, inner, inspecter.Indent, inner))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindStruct:
+		w.WriteString(fmt.Sprintf("%sif (%s_copy(&%s, &%s) != 0) {\n", indent, vt.Struct.Name, dst, src))
+		w.WriteString(fmt.Sprintf("%sgoto fail;\n%s}\n", inner, indent))
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sif (%s != NULL) {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s = calloc(1, sizeof(*%s));\n", inner, dst, dst))
+		w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n%s%sgoto fail;\n%s}\n", inner, dst, inner, inspecter.Indent, inner))
+		c.copyValue(w, inner, inspecter, fmt.Sprintf("(*%s)", dst), fmt.Sprintf("(*%s)", src), vt.Elem, depth)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice, KindMap:
+		index := fmt.Sprintf("i%d", depth)
+		loop := indent
+		count := fmt.Sprintf("%d", vt.Fixed)
+
+		if vt.Fixed == 0 {
+			count = src + "_len"
+			loop = inner
+			w.WriteString(fmt.Sprintf("%sif (%s != NULL && %s > 0) {\n", indent, src, count))
+			w.WriteString(fmt.Sprintf("%s%s = calloc(%s, sizeof(*%s));\n", inner, dst, count, dst))
+			w.WriteString(fmt.Sprintf("%sif (%s == NULL) {\n%s%sgoto fail;\n%s}\n", inner, dst, inner, inspecter.Indent, inner))
+			// set the count first so X_free can release a partial copy
+			w.WriteString(fmt.Sprintf("%s%s_len = %s;\n", inner, dst, count))
+		}
+
+		w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", loop, index, index, count, index))
+		dstElem := fmt.Sprintf("%s[%s]", dst, index)
+		srcElem := fmt.Sprintf("%s[%s]", src, index)
+		if vt.Kind == KindMap {
+			c.copyValue(w, loop+inspecter.Indent, inspecter, dstElem+".key", srcElem+".key", vt.Key, depth+1)
+			dstElem += ".value"
+			srcElem += ".value"
+		}
+		c.copyValue(w, loop+inspecter.Indent, inspecter, dstElem, srcElem, vt.Elem, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", loop))
+
+		if vt.Fixed == 0 {
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+		}
+	}
+}
+
+// equalValue writes the statements returning 0 when a and b differ
+func (c *CLifecycleConverter) equalValue(w *strings.Builder, indent string, inspecter *Inspecter, a string, b string, vt *ValueType, depth int) {
+	inner := indent + inspecter.Indent
+	differ := func(condition string) {
+		w.WriteString(fmt.Sprintf("%sif (%s) {\n%sreturn 0;\n%s}\n", indent, condition, inner, indent))
+	}
+
+	switch vt.Kind {
+	case KindBool, KindInt, KindUint, KindFloat, KindTime:
+		differ(fmt.Sprintf("%s != %s", a, b))
+	case KindString:
+		if vt.Fixed > 0 {
+			differ(fmt.Sprintf("strncmp(%s, %s, %d) != 0", a, b, vt.Fixed))
+		} else {
+			differ(fmt.Sprintf("!gsc_str_equal(%s, %s)", a, b))
+		}
+	case KindStruct:
+		differ(fmt.Sprintf("!%s_equal(&%s, &%s)", vt.Struct.Name, a, b))
+	case KindPointer:
+		differ(fmt.Sprintf("(%s == NULL) != (%s == NULL)", a, b))
+		w.WriteString(fmt.Sprintf("%sif (%s != NULL) {\n", indent, a))
+		c.equalValue(w, inner, inspecter, fmt.Sprintf("(*%s)", a), fmt.Sprintf("(*%s)", b), vt.Elem, depth)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		index := fmt.Sprintf("i%d", depth)
+		count := fmt.Sprintf("%d", vt.Fixed)
+		if vt.Fixed == 0 {
+			count = a + "_len"
+			differ(fmt.Sprintf("%s_len != %s_len", a, b))
+		}
+
+		w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s; %s++) {\n", indent, index, index, count, index))
+		c.equalValue(w, inner, inspecter, fmt.Sprintf("%s[%s]", a, index), fmt.Sprintf("%s[%s]", b, index), vt.Elem, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindMap:
+		// entries can be in any order, so look up every key of a in b
+		index := fmt.Sprintf("i%d", depth)
+		match := fmt.Sprintf("j%d", depth)
+		differ(fmt.Sprintf("%s_len != %s_len", a, b))
+
+		keyA := fmt.Sprintf("%s[%s].key", a, index)
+		keyB := fmt.Sprintf("%s[%s].key", b, match)
+		sameKey := fmt.Sprintf("%s == %s", keyA, keyB)
+		if vt.Key.Kind == KindString {
+			sameKey = fmt.Sprintf("gsc_str_equal(%s, %s)", keyA, keyB)
+		}
+
+		loop := inner + inspecter.Indent
+		w.WriteString(fmt.Sprintf("%sfor (size_t %s = 0; %s < %s_len; %s++) {\n", indent, index, index, a, index))
+		w.WriteString(fmt.Sprintf("%ssize_t %s;\n", inner, match))
+		w.WriteString(fmt.Sprintf("%sfor (%s = 0; %s < %s_len; %s++) {\n", inner, match, match, b, match))
+		w.WriteString(fmt.Sprintf("%sif (%s) {\n%s%sbreak;\n%s}\n", loop, sameKey, loop, inspecter.Indent, loop))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%sif (%s == %s_len) {\n%sreturn 0;\n%s}\n", inner, match, b, loop, inner))
+		c.equalValue(w, inner, inspecter, fmt.Sprintf("%s[%s].value", a, index), fmt.Sprintf("%s[%s].value", b, match), vt.Elem, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (c *CLifecycleConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	w.WriteString(cBanner(inspecter))
+	w.WriteString(cIgnoreDeprecations(inspecter))
+
+	if c.Header != "" {
+		w.WriteString(fmt.Sprintf("#include \"%s\"\n\n", c.Header))
+	}
+
+	w.WriteString("#include <stdlib.h>\n")
+	w.WriteString("#include <string.h>\n\n")
+
+	w.WriteString(cLifecycleHelpers)
+
+	for _, cStruct := range inspecter.Structs {
+		var members []StructMember
+		var types []*ValueType
+		var unknown []StructMember
+
+		for _, member := range cStruct.Members {
+			if member.Type.IsMap && !c.supportedMap(member) {
+				continue
+			}
+
+			vt, err := inspecter.MemberValueType(member)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING! %s, %s.%s is copied shallowly and ignored by %s_free and %s_equal\n", err, cStruct.Name, member.Name, cStruct.Name, cStruct.Name)
+				unknown = append(unknown, member)
+				continue
+			}
+
+			members = append(members, member)
+			types = append(types, vt)
+		}
+
+		indent := inspecter.Indent
+
+		w.WriteString(fmt.Sprintf("void %s_init(%s *x)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%smemset(x, 0, sizeof(*x));\n", indent))
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("void %s_free(%s *x)\n{\n", cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%sif (x == NULL) {\n%s%sreturn;\n%s}\n\n", indent, indent, indent, indent))
+		for i, member := range members {
+			if cNeedsFree(types[i]) {
+				c.freeValue(w, indent, inspecter, "x->"+member.Name, types[i], 0)
+			}
+		}
+		w.WriteString(fmt.Sprintf("%smemset(x, 0, sizeof(*x));\n", indent))
+		w.WriteString("}\n\n")
+
+		usesFail := false
+		var body strings.Builder
+		for i, member := range members {
+			c.copyValue(&body, indent, inspecter, "dst->"+member.Name, "src->"+member.Name, types[i], 0)
+			if cNeedsFree(types[i]) {
+				usesFail = true
+			}
+		}
+		for _, member := range unknown {
+			body.WriteString(fmt.Sprintf("%smemcpy(&dst->%s, &src->%s, sizeof(dst->%s));\n", indent, member.Name, member.Name, member.Name))
+		}
+
+		w.WriteString(fmt.Sprintf("int %s_copy(%s *dst, const %s *src)\n{\n", cStruct.Name, cStruct.Name, cStruct.Name))
+		w.WriteString(fmt.Sprintf("%smemset(dst, 0, sizeof(*dst));\n\n", indent))
+		w.WriteString(body.String())
+		w.WriteString(fmt.Sprintf("\n%sreturn 0;\n", indent))
+		if usesFail {
+			w.WriteString(fmt.Sprintf("\nfail:\n%s%s_free(dst);\n%sreturn -1;\n", indent, cStruct.Name, indent))
+		}
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("int %s_equal(const %s *a, const %s *b)\n{\n", cStruct.Name, cStruct.Name, cStruct.Name))
+		for i, member := range members {
+			c.equalValue(w, indent, inspecter, "a->"+member.Name, "b->"+member.Name, types[i], 0)
+		}
+		if len(members) == 0 {
+			w.WriteString(fmt.Sprintf("%s(void)a;\n%s(void)b;\n", indent, indent))
+		}
+		w.WriteString(fmt.Sprintf("%sreturn 1;\n", indent))
+		w.WriteString("}\n\n")
+	}
+
+	return nil
+}
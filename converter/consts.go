@@ -0,0 +1,185 @@
+package converter
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"strconv"
+)
+
+// ScalarConst is a const declared with a named scalar type, such as
+// KindA Kind = "a", one of the values of an enum
+type ScalarConst struct {
+	Name  string
+	Value constant.Value
+}
+
+// constScope evaluates the consts of a package, the values of the consts
+// declared so far are what later ones can refer to
+type constScope struct {
+	values map[string]constant.Value
+	types  map[string]string
+}
+
+// inspectConsts records the consts declared with the named scalar types of
+// each package in declaration order, once the types of all files are known
+func (inspecter *Inspecter) inspectConsts(asts []ast.Node) {
+	scopes := make(map[string]*constScope)
+
+	for _, node := range asts {
+		f, ok := node.(*ast.File)
+		if !ok {
+			continue
+		}
+
+		pkg := f.Name.Name
+		scope, ok := scopes[pkg]
+		if !ok {
+			scope = &constScope{values: make(map[string]constant.Value), types: make(map[string]string)}
+			scopes[pkg] = scope
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+
+			// a spec without values repeats the type and values of the one before it
+			var typ ast.Expr
+			var values []ast.Expr
+			for n, spec := range gen.Specs {
+				vs := spec.(*ast.ValueSpec)
+				if vs.Type != nil || len(vs.Values) > 0 {
+					typ, values = vs.Type, vs.Values
+				}
+
+				for i, name := range vs.Names {
+					if i >= len(values) {
+						break
+					}
+
+					value := scope.eval(values[i], int64(n))
+					if value == nil || value.Kind() == constant.Unknown {
+						continue
+					}
+					scope.values[name.Name] = value
+
+					scalar := inspecter.constScalar(pkg, scope, typ, values[i])
+					if scalar == nil || name.Name == "_" {
+						// _ = iota skips a value
+						continue
+					}
+					scope.types[name.Name] = scalar.Name
+					scalar.Consts = append(scalar.Consts, ScalarConst{Name: name.Name, Value: value})
+				}
+			}
+		}
+	}
+}
+
+// constScalar finds the named scalar type of a const, declared either by its
+// spec, by a conversion such as Kind("a") or by the const it refers to
+func (inspecter *Inspecter) constScalar(pkg string, scope *constScope, typ ast.Expr, value ast.Expr) *Scalar {
+	if ident, ok := typ.(*ast.Ident); ok {
+		return inspecter.findGoScalar(pkg + "." + ident.Name)
+	}
+	if typ != nil {
+		return nil
+	}
+
+	for {
+		paren, ok := value.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		value = paren.X
+	}
+
+	switch x := value.(type) {
+	case *ast.CallExpr:
+		if ident, ok := x.Fun.(*ast.Ident); ok {
+			return inspecter.findGoScalar(pkg + "." + ident.Name)
+		}
+	case *ast.Ident:
+		if name, ok := scope.types[x.Name]; ok {
+			return inspecter.findGoScalar(pkg + "." + name)
+		}
+	}
+
+	return nil
+}
+
+// eval evaluates a const expression of basic literals, iota, conversions and
+// the consts declared before it, nil when it refers to anything else
+func (scope *constScope) eval(expr ast.Expr, iota int64) constant.Value {
+	switch x := expr.(type) {
+	case *ast.BasicLit:
+		return constant.MakeFromLiteral(x.Value, x.Kind, 0)
+	case *ast.Ident:
+		switch x.Name {
+		case "iota":
+			return constant.MakeInt64(iota)
+		case "true", "false":
+			return constant.MakeBool(x.Name == "true")
+		}
+		return scope.values[x.Name]
+	case *ast.ParenExpr:
+		return scope.eval(x.X, iota)
+	case *ast.CallExpr:
+		// a conversion such as Kind("a") or int64(1)
+		if len(x.Args) != 1 {
+			return nil
+		}
+		return scope.eval(x.Args[0], iota)
+	case *ast.UnaryExpr:
+		v := scope.eval(x.X, iota)
+		if v == nil {
+			return nil
+		}
+		return constant.UnaryOp(x.Op, v, 0)
+	case *ast.BinaryExpr:
+		l := scope.eval(x.X, iota)
+		r := scope.eval(x.Y, iota)
+		if l == nil || r == nil {
+			return nil
+		}
+
+		switch x.Op {
+		case token.SHL, token.SHR:
+			s, ok := constant.Uint64Val(r)
+			if !ok {
+				return nil
+			}
+			return constant.Shift(l, x.Op, uint(s))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			return constant.MakeBool(constant.Compare(l, x.Op, r))
+		case token.QUO:
+			if l.Kind() == constant.Int && r.Kind() == constant.Int {
+				if constant.Sign(r) == 0 {
+					return nil
+				}
+				return constant.BinaryOp(l, token.QUO_ASSIGN, r)
+			}
+		}
+
+		return constant.BinaryOp(l, x.Op, r)
+	}
+
+	return nil
+}
+
+// constLiteral returns the typescript literal of a const value
+func constLiteral(value constant.Value) string {
+	switch value.Kind() {
+	case constant.String:
+		return strconv.Quote(constant.StringVal(value))
+	case constant.Bool:
+		return strconv.FormatBool(constant.BoolVal(value))
+	case constant.Float:
+		f, _ := constant.Float64Val(value)
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return value.ExactString()
+}
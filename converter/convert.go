@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"go/ast"
@@ -24,10 +25,34 @@ type Converter interface {
 	GetTypeFromTags(tags *structtag.Tags) (StructMemberType, bool)
 }
 
+// MultiConverter is implemented by converters that generate several files,
+// such as a header and its implementation, instead of a single one
+type MultiConverter interface {
+	// BuildFiles returns the generated files, named after the output name
+	BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error)
+}
+
+// OutputFile is a generated file, Name has no directory
+type OutputFile struct {
+	Name    string
+	Content string
+}
+
 type Comments struct {
 	CIncludes []string
 
 	TypescriptImports []string
+
+	// Files holds the directives of every input file, split typescript
+	// modules only import the ones of their own files
+	Files []FileComments
+}
+
+// FileComments are the directives found in a single input file
+type FileComments struct {
+	Source            string
+	Package           string
+	TypescriptImports []string
 }
 
 func CleanCInclude(str string) string {
@@ -113,12 +138,15 @@ type Inspecter struct {
 	MappedTypes map[string]string
 	Comments    Comments
 	Indent      string
+	Sources     []string // the input files, set by ConvertFiles
 
 	Structs []Struct
+	Scalars []Scalar
 }
 
 type StructMemberType struct {
 	Value     string
+	GoValue   string // the go type before conversion, e.g. int64 or time.Time
 	Prefix    string
 	Suffix    string
 	IsArray   bool
@@ -131,13 +159,178 @@ type StructMemberType struct {
 type StructMember struct {
 	Name    string
 	Type    StructMemberType
+	GoType  StructMemberType // the go type of the member, ignoring any type overrides from tags
 	Comment string
+	Tags    *structtag.Tags
+
+	Deprecated string // the "Deprecated:" paragraph of the comment, empty unless deprecated
+
+	JSONName      string // the name encoding/json uses for the member
+	JSONOmitEmpty bool
+	JSONString    bool           // the ",string" option, numbers and bools are quoted
+	JSONIgnore    bool           // `json:"-"`
+	Validate      []ValidateRule // the rules of a `validate:"min=2,max=32"` tag that apply to the member itself
+	OneOf         []OneOfVariant // the structs an interface{} member may hold, from a `oneof:"Circle,Square"` tag
+}
+
+// OneOfVariant is one of the struct types an interface{} member may hold
+type OneOfVariant struct {
+	Name string // the go struct name, used as the discriminator
+	Type string // the struct type after the prefix and suffix are applied
 }
 
 type Struct struct {
-	Name    string
-	Members []StructMember
-	Comment string
+	Name       string
+	GoName     string // the name of the struct before a prefix or suffix is applied
+	Package    string
+	Source     string // the input file declaring the struct
+	Anonymous  bool   // nested structs and struct variables are not named go types
+	Members    []StructMember
+	Comment    string
+	Deprecated string // the "Deprecated:" paragraph of the doc comment, empty unless deprecated
+	Directives Directives
+}
+
+// Scalar is a named type declared with a basic go type, such as type UserID int64
+type Scalar struct {
+	Name       string
+	Package    string
+	Source     string // the input file declaring the type
+	GoType     string // the type it is declared with, e.g. int64
+	Comment    string
+	Deprecated string        // the "Deprecated:" paragraph of the doc comment, empty unless deprecated
+	Consts     []ScalarConst // the consts declared with the type, in declaration order
+}
+
+// Directives holds the `//gsc:name=value` comments found above a struct
+type Directives map[string]string
+
+var directiveRe = regexp.MustCompile(`^//[\s]*gsc:([\w.-]+)(?:[\s]*=[\s]*(.*))?$`)
+
+func ParseDirectives(doc *ast.CommentGroup) Directives {
+	directives := Directives{}
+	if doc == nil {
+		return directives
+	}
+
+	for _, comment := range doc.List {
+		matches := directiveRe.FindStringSubmatch(strings.TrimSpace(comment.Text))
+		if len(matches) != 3 {
+			continue
+		}
+
+		directives[matches[1]] = strings.TrimSpace(matches[2])
+	}
+
+	return directives
+}
+
+func (d Directives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+// Int returns the directive value as an integer, 0 if the directive is not set
+func (d Directives) Int(name string) (int, error) {
+	value, ok := d[name]
+	if !ok {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for gsc:%s: %q", name, value)
+	}
+
+	return i, nil
+}
+
+var docSkipRe = regexp.MustCompile(`^[\s]*(gsc:|#[\s]*c.include|#[\s]*ts.import)`)
+
+// ParseDoc joins the text of comments, leaving out directives. A paragraph
+// starting with "Deprecated:" is returned separately.
+func ParseDoc(groups ...*ast.CommentGroup) (string, string) {
+	var paragraphs []string
+	var deprecated []string
+
+	for _, group := range groups {
+		var lines []string
+		flush := func() {
+			if len(lines) == 0 {
+				return
+			}
+
+			paragraph := strings.Join(lines, "\n")
+			if strings.HasPrefix(paragraph, "Deprecated:") {
+				deprecated = append(deprecated, paragraph)
+			} else {
+				paragraphs = append(paragraphs, paragraph)
+			}
+			lines = nil
+		}
+
+		for _, line := range strings.Split(group.Text(), "\n") {
+			if docSkipRe.MatchString(line) {
+				continue
+			}
+
+			if strings.TrimSpace(line) == "" {
+				flush()
+				continue
+			}
+
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+		flush()
+	}
+
+	return strings.Join(paragraphs, "\n\n"), strings.Join(deprecated, "\n\n")
+}
+
+// DeprecatedMessage is the text following "Deprecated:" in a deprecation paragraph
+func DeprecatedMessage(deprecated string) string {
+	return strings.Join(strings.Fields(strings.TrimPrefix(deprecated, "Deprecated:")), " ")
+}
+
+// DocComment formats a comment as a /** */ block understood by doxygen and
+// tsdoc, a deprecation paragraph becomes an @deprecated tag
+func DocComment(indent string, comment string, deprecated string) string {
+	var lines []string
+	if comment != "" {
+		lines = strings.Split(comment, "\n")
+	}
+
+	if deprecated != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.TrimSpace("@deprecated "+DeprecatedMessage(deprecated)))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	for i := range lines {
+		lines[i] = strings.ReplaceAll(lines[i], "*/", "*\\/")
+	}
+
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s/** %s */\n", indent, lines[0])
+	}
+
+	var b strings.Builder
+	b.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		if line == "" {
+			b.WriteString(indent + " *\n")
+		} else {
+			b.WriteString(indent + " * " + line + "\n")
+		}
+	}
+	b.WriteString(indent + " */\n")
+
+	return b.String()
 }
 
 func (inspecter *Inspecter) FileExtension() string {
@@ -150,6 +343,7 @@ func (inspecter *Inspecter) inspectTypes(t ast.Expr, depth int, parent *Struct)
 	case *ast.ArrayType: // TODO
 		if v, ok := t.Elt.(*ast.Ident); ok && v.String() == "byte" {
 			structType.Value = inspecter.Converter.GetIdent("string")
+			structType.GoValue = "[]byte"
 			return structType, nil
 		}
 		res, err := inspecter.inspectTypes(t.Elt, depth, parent)
@@ -160,14 +354,17 @@ func (inspecter *Inspecter) inspectTypes(t ast.Expr, depth int, parent *Struct)
 		return res, nil
 	case *ast.Ident:
 		structType.Value = inspecter.Converter.GetIdent(t.String())
+		structType.GoValue = t.String()
 		return structType, nil
 	case *ast.SelectorExpr:
 		longType := fmt.Sprintf("%s.%s", t.X, t.Sel)
 		structType.Value = inspecter.Converter.GetIdent(longType)
+		structType.GoValue = longType
 
 		return structType, nil
 	case *ast.InterfaceType:
 		structType.Value = inspecter.Converter.GetIdent("interface")
+		structType.GoValue = "interface{}"
 		return structType, nil
 	case *ast.MapType:
 		res := StructMemberType{IsMap: true}
@@ -187,8 +384,6 @@ func (inspecter *Inspecter) inspectTypes(t ast.Expr, depth int, parent *Struct)
 	default:
 		return structType, fmt.Errorf("unhandled: %s, %T", t, t)
 	}
-
-	return structType, nil
 }
 
 func (inspecter *Inspecter) inspectFields(fields []*ast.Field, depth int, parent *Struct) error {
@@ -201,18 +396,15 @@ func (inspecter *Inspecter) inspectFields(fields []*ast.Field, depth int, parent
 			continue
 		}
 
-		comment := f.Comment.Text()
-		if comment != "" {
-			// TODO parse special comments
-		}
+		comment, deprecated := ParseDoc(f.Doc, f.Comment)
 
 		var name string
 		var typeFromTag StructMemberType
 		var typeFromTagExists bool
-		// var validator Validator
-		// usingValidator := false
+		var tags *structtag.Tags
 		if f.Tag != nil {
-			tags, err := structtag.Parse(f.Tag.Value[1 : len(f.Tag.Value)-1])
+			var err error
+			tags, err = structtag.Parse(f.Tag.Value[1 : len(f.Tag.Value)-1])
 			if err != nil {
 				return err
 			}
@@ -228,12 +420,6 @@ func (inspecter *Inspecter) inspectFields(fields []*ast.Field, depth int, parent
 
 			// 	// optional = jsonTag.HasOption("omitempty")
 			// }
-
-			// get validator tag
-			// validatorTag, err := tags.Get("validator")
-			// if err == nil {
-			// 	usingValidator, validator = getValidatorFromTag(validatorTag.String())
-			// }
 		}
 
 		if len(name) == 0 {
@@ -255,41 +441,103 @@ func (inspecter *Inspecter) inspectFields(fields []*ast.Field, depth int, parent
 		}
 
 		member := StructMember{
-			Name:    name,
-			Comment: comment,
+			Name:       name,
+			Comment:    comment,
+			Deprecated: deprecated,
+			Tags:       tags,
+			JSONName:   fieldName,
 			Type: StructMemberType{
 				IsPointer: isPointer,
 			},
 		}
 
-		if typeFromTagExists {
-			member.Type = typeFromTag
-		} else {
-			switch t := f.Type.(type) {
-			case *ast.StructType:
-				// Nested struct, deal with it
-				newStruct := Struct{
-					Name: name,
+		if tags != nil {
+			jsonTag, err := tags.Get("json")
+			if err == nil {
+				if jsonTag.Name == "-" && len(jsonTag.Options) == 0 {
+					member.JSONIgnore = true
+				} else if jsonTag.Name != "" {
+					member.JSONName = jsonTag.Name
 				}
 
-				err := inspecter.inspectFields(t.Fields.List, 0, &newStruct)
-				if err != nil {
-					return err
-				}
+				member.JSONOmitEmpty = jsonTag.HasOption("omitempty")
+				member.JSONString = jsonTag.HasOption("string")
+			}
 
-				inspecter.Structs = append(inspecter.Structs, newStruct)
+			oneOfTag, err := tags.Get("oneof")
+			if err == nil {
+				for _, variant := range strings.Split(oneOfTag.Value(), ",") {
+					variant = strings.TrimSpace(variant)
+					if variant == "" {
+						continue
+					}
 
-				newName := inspecter.Prefix + name + inspecter.Suffix
-				inspecter.MappedTypes[name] = newName
+					if lo.ContainsBy(member.OneOf, func(v OneOfVariant) bool { return v.Name == variant }) {
+						return fmt.Errorf("%s: %s is listed twice in oneof", name, variant)
+					}
 
-				member.Type.Value = name
-			default:
-				res, err := inspecter.inspectTypes(f.Type, depth, parent)
-				if err != nil {
-					return err
+					member.OneOf = append(member.OneOf, OneOfVariant{Name: variant, Type: variant})
 				}
 
-				member.Type = res
+				if len(member.OneOf) == 0 {
+					return fmt.Errorf("%s: oneof needs at least one struct", name)
+				}
+			}
+
+			validateTag, err := tags.Get("validate")
+			if err == nil {
+				member.Validate = ParseValidateTag(validateTag.Value())
+			}
+		}
+
+		switch t := f.Type.(type) {
+		case *ast.StructType:
+			if typeFromTagExists {
+				break
+			}
+
+			// Nested struct, deal with it
+			newStruct := Struct{
+				Name:       name,
+				GoName:     name,
+				Package:    parent.Package,
+				Source:     parent.Source,
+				Anonymous:  true,
+				Directives: ParseDirectives(f.Doc),
+			}
+
+			err := inspecter.inspectFields(t.Fields.List, 0, &newStruct)
+			if err != nil {
+				return err
+			}
+
+			inspecter.Structs = append(inspecter.Structs, newStruct)
+
+			newName := inspecter.Prefix + name + inspecter.Suffix
+			inspecter.MappedTypes[name] = newName
+
+			member.Type.Value = name
+			member.Type.GoValue = name
+			member.GoType = member.Type
+		default:
+			res, err := inspecter.inspectTypes(f.Type, depth, parent)
+			if err != nil && !typeFromTagExists {
+				return err
+			}
+
+			res.IsPointer = isPointer
+			member.Type = res
+			member.GoType = res
+		}
+
+		if typeFromTagExists {
+			member.Type = typeFromTag
+		}
+
+		if len(member.OneOf) > 0 {
+			t := member.GoType
+			if (t.GoValue != "interface{}" && t.GoValue != "any") || t.IsPointer || t.IsArray || t.IsMap {
+				return fmt.Errorf("%s: oneof needs an interface{} field", name)
 			}
 		}
 
@@ -303,8 +551,16 @@ func (inspecter *Inspecter) inspectFields(fields []*ast.Field, depth int, parent
 func (inspecter *Inspecter) inspectNodes(asts []ast.Node) error {
 	var err error
 	var name string
+	var pkg string
+	var source string
+	var anonymous bool
+	var doc *ast.CommentGroup
+
+	for i, f := range asts {
+		if i < len(inspecter.Sources) {
+			source = inspecter.Sources[i]
+		}
 
-	for _, f := range asts {
 		ast.Inspect(f, func(n ast.Node) bool {
 			if err != nil {
 				return false
@@ -312,13 +568,59 @@ func (inspecter *Inspecter) inspectNodes(asts []ast.Node) error {
 
 			switch x := n.(type) {
 			case *ast.File:
+				pkg = x.Name.Name
 				err = HandleFileComments(x.Comments, &inspecter.Comments)
+				if err != nil {
+					return false
+				}
+
+				var file Comments
+				err = HandleFileComments(x.Comments, &file)
+				inspecter.Comments.Files = append(inspecter.Comments.Files, FileComments{Source: source, Package: pkg, TypescriptImports: file.TypescriptImports})
+			case *ast.GenDecl:
+				doc = x.Doc
+				anonymous = x.Tok != token.TYPE
+			case *ast.TypeSpec:
+				if x.Doc != nil {
+					doc = x.Doc
+				}
+
+				// type UserID int64 declares a named scalar type, aliases are the same type
+				if basic, ok := x.Type.(*ast.Ident); ok && !x.Assign.IsValid() && x.TypeParams == nil {
+					comment, deprecated := ParseDoc(doc)
+					inspecter.Scalars = append(inspecter.Scalars, Scalar{
+						Name:       x.Name.Name,
+						Package:    pkg,
+						Source:     source,
+						GoType:     basic.Name,
+						Comment:    comment,
+						Deprecated: deprecated,
+					})
+					doc = nil
+
+					inspecter.MappedTypes[pkg+"."+x.Name.Name] = x.Name.Name
+
+					return false
+				}
+			case *ast.ValueSpec:
+				if x.Doc != nil {
+					doc = x.Doc
+				}
 			case *ast.Ident:
 				name = x.Name
 			case *ast.StructType:
+				comment, deprecated := ParseDoc(doc)
 				newStruct := Struct{
-					Name: name,
+					Name:       name,
+					GoName:     name,
+					Package:    pkg,
+					Source:     source,
+					Anonymous:  anonymous,
+					Comment:    comment,
+					Deprecated: deprecated,
+					Directives: ParseDirectives(doc),
 				}
+				doc = nil
 
 				err = inspecter.inspectFields(x.Fields.List, 0, &newStruct)
 				if err != nil {
@@ -330,6 +632,8 @@ func (inspecter *Inspecter) inspectNodes(asts []ast.Node) error {
 				newName := inspecter.Prefix + name + inspecter.Suffix
 
 				inspecter.MappedTypes[name] = newName
+				// other packages refer to it as pkg.Name
+				inspecter.MappedTypes[pkg+"."+name] = newName
 
 				return false
 			}
@@ -341,6 +645,16 @@ func (inspecter *Inspecter) inspectNodes(asts []ast.Node) error {
 }
 
 func (inspecter *Inspecter) convert(w *strings.Builder, asts []ast.Node) error {
+	err := inspecter.prepare(asts)
+	if err != nil {
+		return err
+	}
+
+	return inspecter.Converter.Builder(w, inspecter)
+}
+
+// prepare collects the structs of the parsed files and applies the prefix and suffix
+func (inspecter *Inspecter) prepare(asts []ast.Node) error {
 	var err error
 	inspecter.MappedTypes = make(map[string]string)
 
@@ -353,6 +667,7 @@ func (inspecter *Inspecter) convert(w *strings.Builder, asts []ast.Node) error {
 	if err != nil {
 		return err
 	}
+	inspecter.inspectConsts(asts)
 
 	for i := range inspecter.Structs {
 		renamed, ok := inspecter.MappedTypes[inspecter.Structs[i].Name]
@@ -361,17 +676,89 @@ func (inspecter *Inspecter) convert(w *strings.Builder, asts []ast.Node) error {
 		}
 
 		for j := range inspecter.Structs[i].Members {
-			renamed, ok := inspecter.MappedTypes[inspecter.Structs[i].Members[j].Type.Value]
-			if ok {
-				inspecter.Structs[i].Members[j].Type.Value = renamed
+			member := &inspecter.Structs[i].Members[j]
+			inspecter.renameType(&member.Type)
+
+			for k := range member.OneOf {
+				renamed, ok := inspecter.MappedTypes[member.OneOf[k].Name]
+				if !ok {
+					return fmt.Errorf("%s: unknown oneof struct %s", member.Name, member.OneOf[k].Name)
+				}
+				member.OneOf[k].Type = renamed
 			}
 		}
 	}
 
-	return inspecter.Converter.Builder(w, inspecter)
+	return nil
+}
+
+// renameType applies the prefix and suffix to the struct types used by a member, including map keys and values
+func (inspecter *Inspecter) renameType(t *StructMemberType) {
+	renamed, ok := inspecter.MappedTypes[t.Value]
+	if ok {
+		t.Value = renamed
+	}
+
+	if t.MapKey != nil {
+		key := *t.MapKey
+		inspecter.renameType(&key)
+		t.MapKey = &key
+	}
+
+	if t.MapVal != nil {
+		val := *t.MapVal
+		inspecter.renameType(&val)
+		t.MapVal = &val
+	}
 }
 
 func (inspecter *Inspecter) ConvertFiles(inputs []string) (*strings.Builder, error) {
+	asts, err := inspecter.parseFiles(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := new(strings.Builder)
+	err = inspecter.convert(builder, asts)
+	if err != nil {
+		return nil, err
+	}
+
+	return builder, nil
+}
+
+// ConvertToFiles parses the inputs and returns the generated files named
+// after name, a single name.<extension> unless the converter is a MultiConverter
+func (inspecter *Inspecter) ConvertToFiles(inputs []string, name string) ([]OutputFile, error) {
+	asts, err := inspecter.parseFiles(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = inspecter.prepare(asts)
+	if err != nil {
+		return nil, err
+	}
+
+	if multi, ok := inspecter.Converter.(MultiConverter); ok {
+		return multi.BuildFiles(name, inspecter)
+	}
+
+	return buildFile(inspecter.Converter, name, inspecter)
+}
+
+// buildFile generates the single name.<extension> file of a converter
+func buildFile(converter Converter, name string, inspecter *Inspecter) ([]OutputFile, error) {
+	builder := new(strings.Builder)
+	err := converter.Builder(builder, inspecter)
+	if err != nil {
+		return nil, err
+	}
+
+	return []OutputFile{{Name: fmt.Sprintf("%s.%s", name, converter.FileExtension()), Content: builder.String()}}, nil
+}
+
+func (inspecter *Inspecter) parseFiles(inputs []string) ([]ast.Node, error) {
 	var asts []ast.Node
 
 	for _, filename := range inputs {
@@ -406,11 +793,7 @@ func (inspecter *Inspecter) ConvertFiles(inputs []string) (*strings.Builder, err
 		asts = append(asts, f)
 	}
 
-	builder := new(strings.Builder)
-	err := inspecter.convert(builder, asts)
-	if err != nil {
-		return nil, err
-	}
+	inspecter.Sources = inputs
 
-	return builder, nil
+	return asts, nil
 }
@@ -0,0 +1,51 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPointerMembersKeepIsPointer(t *testing.T) {
+	input := writeInput(t, `package example
+
+type User struct {
+	Name  *string
+	Count int
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members := inspecter.Structs[0].Members
+	if !members[0].Type.IsPointer || members[1].Type.IsPointer {
+		t.Errorf("expected only Name to be a pointer, got %+v", members)
+	}
+
+	// the pointer nullability model makes pointers optional
+	if !strings.Contains(files[0].Content, "Name?: string;") || !strings.Contains(files[0].Content, "Count: number;") {
+		t.Errorf("unexpected interface:\n%s", files[0].Content)
+	}
+}
+
+func TestCTypedefAppliesPrefixAndSuffixOnce(t *testing.T) {
+	input := writeInput(t, `package example
+
+type User struct {
+	Count int
+}
+`)
+
+	inspecter := &Inspecter{Converter: &CConverter{}, Indent: "\t", Prefix: "P_", Suffix: "_t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(files[0].Content, "} P_User_t;") || strings.Contains(files[0].Content, "P_P_") {
+		t.Errorf("unexpected typedef name:\n%s", files[0].Content)
+	}
+}
@@ -0,0 +1,793 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/structtag"
+)
+
+// CppConverter generates C++17 structs using the standard library containers.
+// Slices become std::vector, maps std::map and pointers std::optional (or
+// std::shared_ptr when the pointer refers back to a struct that is still
+// being declared).
+type CppConverter struct {
+	Namespace string // wrap the structs in a namespace
+	JSON      bool   // generate nlohmann::json to_json and from_json functions
+
+	shared map[string]bool // "Struct.Member" pointers that must be std::shared_ptr
+}
+
+const cppTimePoint = "std::chrono::time_point<std::chrono::system_clock, std::chrono::nanoseconds>"
+
+func (c *CppConverter) GetIdent(s string) string {
+	switch s {
+	case "byte":
+		return "std::uint8_t"
+	case "string":
+		return "std::string"
+	case "bool":
+		return "bool"
+	case "int":
+		return "std::int64_t"
+	case "uint":
+		return "std::uint64_t"
+	case "rune":
+		return "std::int32_t"
+	case "float32":
+		return "float"
+	case "float64":
+		return "double"
+	case "int8", "int16", "int32", "int64",
+		"uint8", "uint16", "uint32", "uint64":
+		return fmt.Sprintf("std::%s_t", s)
+	case "time.Time":
+		return cppTimePoint
+	case "decimal.Decimal":
+		return "double"
+	case "interface", "interface{}":
+		if c.JSON {
+			return "nlohmann::json"
+		}
+		return "std::any"
+	}
+
+	// types from other packages are expected to live in a namespace of the same name
+	return strings.ReplaceAll(s, ".", "::")
+}
+
+func (c *CppConverter) GetTypeFromTags(tags *structtag.Tags) (StructMemberType, bool) {
+	memberType := StructMemberType{}
+
+	cppTypeTag, err := tags.Get("cpptype")
+	if err == nil {
+		idx := strings.Index(cppTypeTag.Name, "[")
+		if idx > 0 {
+			memberType.Value = cppTypeTag.Name[0:idx]
+			memberType.Suffix = cppTypeTag.Name[idx:]
+		} else {
+			memberType.Value = cppTypeTag.Name
+		}
+
+		return memberType, true
+	}
+
+	return memberType, false
+}
+
+var CppValidNameRegexp = regexp.MustCompile(`(?m)^[\pL_][\pL\pN_]*$`)
+
+func (c *CppConverter) ValidName(n string) bool {
+	return CppValidNameRegexp.MatchString(n)
+}
+
+func (c *CppConverter) FileExtension() string {
+	return "hpp"
+}
+
+// cppType returns the C++ type of a member, key or value. Types named after
+// a member of the struct being declared are written as `struct X` because the
+// member would otherwise change the meaning of the name.
+func (c *CppConverter) cppType(t StructMemberType, shared bool, clashes map[string]bool) string {
+	var base string
+	if t.IsMap {
+		base = fmt.Sprintf("std::map<%s, %s>", c.cppType(*t.MapKey, false, clashes), c.cppType(*t.MapVal, false, clashes))
+	} else if t.GoValue == "[]byte" {
+		base = "std::vector<std::uint8_t>"
+	} else if clashes[t.Value] {
+		base = "struct " + t.Prefix + t.Value
+	} else {
+		base = t.Prefix + t.Value
+	}
+
+	if t.IsArray {
+		base = fmt.Sprintf("std::vector<%s>", base)
+	}
+
+	if t.IsPointer {
+		if shared {
+			base = fmt.Sprintf("std::shared_ptr<%s>", base)
+		} else {
+			base = fmt.Sprintf("std::optional<%s>", base)
+		}
+	}
+
+	return base
+}
+
+// referencedStructs returns the names of the structs used by a member type
+func referencedStructs(t StructMemberType, names map[string]bool) []string {
+	var refs []string
+	if t.IsMap {
+		if t.MapKey != nil {
+			refs = append(refs, referencedStructs(*t.MapKey, names)...)
+		}
+		if t.MapVal != nil {
+			refs = append(refs, referencedStructs(*t.MapVal, names)...)
+		}
+	} else if names[t.Value] {
+		refs = append(refs, t.Value)
+	}
+
+	return refs
+}
+
+// orderStructs sorts the structs so every struct is declared before the
+// structs using it. std::vector and std::map accept the forward declared
+// types of a cycle but std::optional does not, so pointers that refer back to
+// a struct still being declared are marked as shared.
+func (c *CppConverter) orderStructs(inspecter *Inspecter) []Struct {
+	byName := make(map[string]Struct)
+	names := make(map[string]bool)
+	for _, cppStruct := range inspecter.Structs {
+		byName[cppStruct.Name] = cppStruct
+		names[cppStruct.Name] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[string]int)
+	c.shared = make(map[string]bool)
+	var ordered []Struct
+
+	var visit func(cppStruct Struct)
+	visit = func(cppStruct Struct) {
+		state[cppStruct.Name] = visiting
+
+		for _, member := range cppStruct.Members {
+			for _, ref := range referencedStructs(member.Type, names) {
+				switch state[ref] {
+				case visiting:
+					if member.Type.IsPointer && !member.Type.IsArray && !member.Type.IsMap {
+						c.shared[cppStruct.Name+"."+member.Name] = true
+					}
+				case done:
+				default:
+					visit(byName[ref])
+				}
+			}
+		}
+
+		state[cppStruct.Name] = done
+		ordered = append(ordered, cppStruct)
+	}
+
+	for _, cppStruct := range inspecter.Structs {
+		if state[cppStruct.Name] == 0 {
+			visit(cppStruct)
+		}
+	}
+
+	return ordered
+}
+
+// cppQuotable is whether the ",string" json option applies to a member
+func cppQuotable(member StructMember) bool {
+	if !member.JSONString || member.GoType.IsArray || member.GoType.IsMap {
+		return false
+	}
+
+	_, ok := goNumbers[member.GoType.GoValue]
+	return ok
+}
+
+func (c *CppConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	structs := c.orderStructs(inspecter)
+
+	var body strings.Builder
+
+	if c.Namespace != "" {
+		body.WriteString(fmt.Sprintf("namespace %s {\n\n", c.Namespace))
+	}
+
+	for _, cppStruct := range structs {
+		body.WriteString(fmt.Sprintf("struct %s;\n", cppStruct.Name))
+	}
+	body.WriteString("\n")
+
+	names := make(map[string]bool)
+	for _, cppStruct := range structs {
+		names[cppStruct.Name] = true
+	}
+
+	for _, cppStruct := range structs {
+		body.WriteString(DocComment("", cppStruct.Comment, cppStruct.Deprecated))
+		body.WriteString(fmt.Sprintf("struct %s {\n", cppStruct.Name))
+
+		clashes := make(map[string]bool)
+		for _, member := range cppStruct.Members {
+			if names[member.Name] {
+				clashes[member.Name] = true
+			}
+		}
+
+		for _, member := range cppStruct.Members {
+			t := c.cppType(member.Type, c.shared[cppStruct.Name+"."+member.Name], clashes)
+			body.WriteString(DocComment(inspecter.Indent, member.Comment, member.Deprecated))
+			body.WriteString(fmt.Sprintf("%s%s %s%s", inspecter.Indent, t, member.Name, member.Type.Suffix))
+
+			// containers and std::string default construct to empty, everything else is zeroed
+			if !member.Type.IsArray && !member.Type.IsMap && !member.Type.IsPointer && member.Type.GoValue != "[]byte" && t != "std::string" && t != "std::any" && t != "nlohmann::json" {
+				body.WriteString("{}")
+			}
+			body.WriteString(";\n")
+		}
+
+		body.WriteString("};\n\n")
+	}
+
+	if c.JSON {
+		c.jsonFunctions(&body, inspecter, structs)
+	}
+
+	if c.Namespace != "" {
+		body.WriteString(fmt.Sprintf("} // namespace %s\n", c.Namespace))
+	}
+
+	generated := body.String()
+
+	includes := inspecter.Comments.CIncludes
+	includes = appendCInclude(includes, "<cstdint>")
+
+	for _, header := range []struct {
+		use     string
+		include string
+	}{
+		{"std::any", "<any>"},
+		{"std::chrono::", "<chrono>"},
+		{"std::map<", "<map>"},
+		{"std::shared_ptr<", "<memory>"},
+		{"std::optional<", "<optional>"},
+		{"std::string", "<string>"},
+		{"std::vector<", "<vector>"},
+	} {
+		if strings.Contains(generated, header.use) {
+			includes = appendCInclude(includes, header.include)
+		}
+	}
+
+	if c.JSON {
+		// used by the helpers
+		for _, include := range []string{"<cctype>", "<chrono>", "<cstdio>", "<cstring>", "<map>", "<memory>", "<optional>", "<stdexcept>", "<string>", "<vector>", "<nlohmann/json.hpp>"} {
+			includes = appendCInclude(includes, include)
+		}
+	}
+
+	w.WriteString(cBanner(inspecter))
+	w.WriteString("#pragma once\n\n")
+	for _, include := range includes {
+		w.WriteString(fmt.Sprintf("#include %s\n", include))
+	}
+	w.WriteString("\n")
+
+	if c.JSON {
+		w.WriteString(cppJSONHelpers)
+		w.WriteString("\n")
+	}
+
+	w.WriteString(generated)
+
+	return nil
+}
+
+// jsonFunctions writes the to_json and from_json functions found by
+// nlohmann::json through argument dependent lookup. Members are keyed by their
+// json tag names and follow encoding/json for omitempty, ",string" and "-".
+func (c *CppConverter) jsonFunctions(w *strings.Builder, inspecter *Inspecter, structs []Struct) {
+	for _, cppStruct := range structs {
+		w.WriteString(fmt.Sprintf("inline void to_json(nlohmann::json &j, const %s &x);\n", cppStruct.Name))
+		w.WriteString(fmt.Sprintf("inline void from_json(const nlohmann::json &j, %s &x);\n", cppStruct.Name))
+	}
+	w.WriteString("\n")
+
+	for _, cppStruct := range structs {
+		var members []StructMember
+		for _, member := range cppStruct.Members {
+			if member.JSONIgnore {
+				continue
+			}
+
+			if member.Type.Suffix != "" {
+				fmt.Fprintf(os.Stderr, "WARNING! fixed size arrays are unsupported by json, skipping %s.%s\n", cppStruct.Name, member.Name)
+				continue
+			}
+
+			members = append(members, member)
+		}
+
+		w.WriteString(fmt.Sprintf("inline void to_json(nlohmann::json &j, const %s &x)\n{\n", cppStruct.Name))
+		w.WriteString(fmt.Sprintf("%sj = nlohmann::json::object();\n", inspecter.Indent))
+		for _, member := range members {
+			value := fmt.Sprintf("gsc::to_json_value(x.%s)", member.Name)
+			if cppQuotable(member) {
+				value = fmt.Sprintf("gsc::to_json_quoted(x.%s)", member.Name)
+			}
+
+			if member.JSONOmitEmpty {
+				w.WriteString(fmt.Sprintf("%sif (!gsc::is_empty(x.%s)) {\n", inspecter.Indent, member.Name))
+				w.WriteString(fmt.Sprintf("%s%sj[%q] = %s;\n", inspecter.Indent, inspecter.Indent, member.JSONName, value))
+				w.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+			} else {
+				w.WriteString(fmt.Sprintf("%sj[%q] = %s;\n", inspecter.Indent, member.JSONName, value))
+			}
+		}
+		w.WriteString("}\n\n")
+
+		w.WriteString(fmt.Sprintf("inline void from_json(const nlohmann::json &j, %s &x)\n{\n", cppStruct.Name))
+		w.WriteString(fmt.Sprintf("%sif (!j.is_object()) {\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s%sthrow std::invalid_argument(\"%s: expected a json object\");\n", inspecter.Indent, inspecter.Indent, cppStruct.Name))
+		w.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+		for _, member := range members {
+			read := "from_json_value"
+			if cppQuotable(member) {
+				read = "from_json_quoted"
+			}
+
+			w.WriteString(fmt.Sprintf("%sif (const nlohmann::json *v = gsc::find(j, %q)) {\n", inspecter.Indent, member.JSONName))
+			w.WriteString(fmt.Sprintf("%s%sgsc::%s(*v, x.%s);\n", inspecter.Indent, inspecter.Indent, read, member.Name))
+			w.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+		}
+		w.WriteString("}\n\n")
+	}
+}
+
+// cppJSONHelpers are shared by every generated header, the guard keeps them
+// from being defined twice when several headers are included together
+const cppJSONHelpers = `#ifndef GSC_NLOHMANN_JSON_HELPERS
+#define GSC_NLOHMANN_JSON_HELPERS
+
+namespace gsc {
+
+using time_point = std::chrono::time_point<std::chrono::system_clock, std::chrono::nanoseconds>;
+
+template <typename T> nlohmann::json to_json_value(const T &v);
+template <typename T> nlohmann::json to_json_value(const std::optional<T> &v);
+template <typename T> nlohmann::json to_json_value(const std::shared_ptr<T> &v);
+template <typename T> nlohmann::json to_json_value(const std::vector<T> &v);
+template <typename K, typename V> nlohmann::json to_json_value(const std::map<K, V> &v);
+inline nlohmann::json to_json_value(const std::vector<std::uint8_t> &v);
+inline nlohmann::json to_json_value(const time_point &v);
+
+template <typename T> void from_json_value(const nlohmann::json &j, T &v);
+template <typename T> void from_json_value(const nlohmann::json &j, std::optional<T> &v);
+template <typename T> void from_json_value(const nlohmann::json &j, std::shared_ptr<T> &v);
+template <typename T> void from_json_value(const nlohmann::json &j, std::vector<T> &v);
+template <typename K, typename V> void from_json_value(const nlohmann::json &j, std::map<K, V> &v);
+inline void from_json_value(const nlohmann::json &j, std::vector<std::uint8_t> &v);
+inline void from_json_value(const nlohmann::json &j, time_point &v);
+
+// encoding/json matches names case insensitively, preferring an exact match
+inline const nlohmann::json *find(const nlohmann::json &j, const char *name)
+{
+	auto it = j.find(name);
+	if (it != j.end()) {
+		return &*it;
+	}
+
+	for (auto &item : j.items()) {
+		const std::string &key = item.key();
+		size_t i = 0;
+		while (i < key.size() && name[i] != '\0' && std::tolower((unsigned char)key[i]) == std::tolower((unsigned char)name[i])) {
+			i++;
+		}
+
+		if (i == key.size() && name[i] == '\0') {
+			return &item.value();
+		}
+	}
+
+	return nullptr;
+}
+
+template <typename T> bool is_empty(const T &v)
+{
+	if constexpr (std::is_arithmetic_v<T>) {
+		return v == T{};
+	} else {
+		return false;
+	}
+}
+
+inline bool is_empty(const std::string &v) { return v.empty(); }
+template <typename T> bool is_empty(const std::optional<T> &v) { return !v.has_value(); }
+template <typename T> bool is_empty(const std::shared_ptr<T> &v) { return v == nullptr; }
+template <typename T> bool is_empty(const std::vector<T> &v) { return v.empty(); }
+template <typename K, typename V> bool is_empty(const std::map<K, V> &v) { return v.empty(); }
+
+template <typename T> nlohmann::json to_json_value(const T &v)
+{
+	return nlohmann::json(v);
+}
+
+template <typename T> nlohmann::json to_json_value(const std::optional<T> &v)
+{
+	if (!v.has_value()) {
+		return nullptr;
+	}
+
+	return to_json_value(*v);
+}
+
+template <typename T> nlohmann::json to_json_value(const std::shared_ptr<T> &v)
+{
+	if (v == nullptr) {
+		return nullptr;
+	}
+
+	return to_json_value(*v);
+}
+
+template <typename T> nlohmann::json to_json_value(const std::vector<T> &v)
+{
+	nlohmann::json j = nlohmann::json::array();
+	for (const auto &item : v) {
+		j.push_back(to_json_value(item));
+	}
+
+	return j;
+}
+
+template <typename K, typename V> nlohmann::json to_json_value(const std::map<K, V> &v)
+{
+	nlohmann::json j = nlohmann::json::object();
+	for (const auto &item : v) {
+		if constexpr (std::is_same_v<K, std::string>) {
+			j[item.first] = to_json_value(item.second);
+		} else {
+			j[std::to_string(item.first)] = to_json_value(item.second);
+		}
+	}
+
+	return j;
+}
+
+inline constexpr char base64_chars[] = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+
+// go encodes []byte as a base64 string
+inline nlohmann::json to_json_value(const std::vector<std::uint8_t> &v)
+{
+	std::string s;
+	for (size_t i = 0; i < v.size(); i += 3) {
+		std::uint32_t n = (std::uint32_t)v[i] << 16;
+		if (i + 1 < v.size()) {
+			n |= (std::uint32_t)v[i + 1] << 8;
+		}
+		if (i + 2 < v.size()) {
+			n |= v[i + 2];
+		}
+
+		s += base64_chars[(n >> 18) & 63];
+		s += base64_chars[(n >> 12) & 63];
+		s += i + 1 < v.size() ? base64_chars[(n >> 6) & 63] : '=';
+		s += i + 2 < v.size() ? base64_chars[n & 63] : '=';
+	}
+
+	return s;
+}
+
+inline std::int64_t days_from_civil(std::int64_t y, unsigned m, unsigned d)
+{
+	y -= m <= 2;
+	std::int64_t era = (y >= 0 ? y : y - 399) / 400;
+	unsigned yoe = (unsigned)(y - era * 400);
+	unsigned doy = (153 * (m > 2 ? m - 3 : m + 9) + 2) / 5 + d - 1;
+	unsigned doe = yoe * 365 + yoe / 4 - yoe / 100 + doy;
+	return era * 146097 + (std::int64_t)doe - 719468;
+}
+
+inline void civil_from_days(std::int64_t z, std::int64_t &y, unsigned &m, unsigned &d)
+{
+	z += 719468;
+	std::int64_t era = (z >= 0 ? z : z - 146096) / 146097;
+	unsigned doe = (unsigned)(z - era * 146097);
+	unsigned yoe = (doe - doe / 1460 + doe / 36524 - doe / 146096) / 365;
+	unsigned doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+	unsigned mp = (5 * doy + 2) / 153;
+	d = doy - (153 * mp + 2) / 5 + 1;
+	m = mp < 10 ? mp + 3 : mp - 9;
+	y = (std::int64_t)yoe + era * 400 + (m <= 2);
+}
+
+// go encodes time.Time as an RFC 3339 string, the epoch is the zero time
+inline nlohmann::json to_json_value(const time_point &v)
+{
+	std::int64_t ns = v.time_since_epoch().count();
+	if (ns == 0) {
+		return "0001-01-01T00:00:00Z";
+	}
+
+	std::int64_t secs = ns / 1000000000;
+	std::int64_t nsec = ns % 1000000000;
+	if (nsec < 0) {
+		nsec += 1000000000;
+		secs--;
+	}
+
+	std::int64_t days = secs / 86400;
+	std::int64_t rem = secs % 86400;
+	if (rem < 0) {
+		rem += 86400;
+		days--;
+	}
+
+	std::int64_t y;
+	unsigned m, d;
+	civil_from_days(days, y, m, d);
+
+	char buf[64];
+	int n = std::snprintf(buf, sizeof(buf), "%04lld-%02u-%02uT%02d:%02d:%02d", (long long)y, m, d, (int)(rem / 3600), (int)(rem / 60 % 60), (int)(rem % 60));
+	if (nsec != 0) {
+		n += std::snprintf(buf + n, sizeof(buf) - n, ".%09lld", (long long)nsec);
+		while (buf[n - 1] == '0') {
+			n--;
+		}
+	}
+
+	return std::string(buf, n) + "Z";
+}
+
+template <typename T> nlohmann::json to_json_quoted(const T &v)
+{
+	if constexpr (std::is_same_v<T, bool>) {
+		return v ? "true" : "false";
+	} else if constexpr (std::is_floating_point_v<T>) {
+		char buf[32];
+		std::snprintf(buf, sizeof(buf), "%.17g", (double)v);
+		return std::string(buf);
+	} else {
+		return std::to_string(v);
+	}
+}
+
+template <typename T> nlohmann::json to_json_quoted(const std::optional<T> &v)
+{
+	if (!v.has_value()) {
+		return nullptr;
+	}
+
+	return to_json_quoted(*v);
+}
+
+// null leaves the value untouched like encoding/json
+template <typename T> void from_json_value(const nlohmann::json &j, T &v)
+{
+	if (j.is_null()) {
+		return;
+	}
+
+	if constexpr (std::is_same_v<T, nlohmann::json>) {
+		v = j;
+	} else {
+		j.get_to(v);
+	}
+}
+
+template <typename T> void from_json_value(const nlohmann::json &j, std::optional<T> &v)
+{
+	if (j.is_null()) {
+		v.reset();
+		return;
+	}
+
+	v.emplace();
+	from_json_value(j, *v);
+}
+
+template <typename T> void from_json_value(const nlohmann::json &j, std::shared_ptr<T> &v)
+{
+	if (j.is_null()) {
+		v.reset();
+		return;
+	}
+
+	v = std::make_shared<T>();
+	from_json_value(j, *v);
+}
+
+template <typename T> void from_json_value(const nlohmann::json &j, std::vector<T> &v)
+{
+	if (j.is_null()) {
+		v.clear();
+		return;
+	}
+
+	if (!j.is_array()) {
+		throw std::invalid_argument("expected a json array");
+	}
+
+	v.clear();
+	v.resize(j.size());
+	for (size_t i = 0; i < j.size(); i++) {
+		from_json_value(j[i], v[i]);
+	}
+}
+
+template <typename K> K map_key(const std::string &key)
+{
+	if constexpr (std::is_same_v<K, std::string>) {
+		return key;
+	} else if constexpr (std::is_signed_v<K>) {
+		return (K)std::stoll(key);
+	} else {
+		return (K)std::stoull(key);
+	}
+}
+
+template <typename K, typename V> void from_json_value(const nlohmann::json &j, std::map<K, V> &v)
+{
+	if (j.is_null()) {
+		v.clear();
+		return;
+	}
+
+	if (!j.is_object()) {
+		throw std::invalid_argument("expected a json object");
+	}
+
+	v.clear();
+	for (auto &item : j.items()) {
+		V value{};
+		from_json_value(item.value(), value);
+		v.emplace(map_key<K>(item.key()), std::move(value));
+	}
+}
+
+inline void from_json_value(const nlohmann::json &j, std::vector<std::uint8_t> &v)
+{
+	v.clear();
+	if (j.is_null()) {
+		return;
+	}
+
+	const std::string &s = j.get_ref<const std::string &>();
+	std::uint32_t n = 0;
+	int bits = 0;
+	for (char ch : s) {
+		if (ch == '=') {
+			break;
+		}
+
+		const char *p = std::strchr(base64_chars, ch);
+		if (p == nullptr || ch == '\0') {
+			throw std::invalid_argument("invalid base64");
+		}
+
+		n = (n << 6) | (std::uint32_t)(p - base64_chars);
+		bits += 6;
+		if (bits >= 8) {
+			bits -= 8;
+			v.push_back((std::uint8_t)((n >> bits) & 0xff));
+		}
+	}
+}
+
+inline void from_json_value(const nlohmann::json &j, time_point &v)
+{
+	if (j.is_null()) {
+		return;
+	}
+
+	const std::string &str = j.get_ref<const std::string &>();
+	const char *s = str.c_str();
+	int year, mon, day, hour, min, sec, n = 0, digits = 0;
+	std::int64_t frac = 0, offset = 0;
+
+	if (std::sscanf(s, "%4d-%2d-%2dT%2d:%2d:%2d%n", &year, &mon, &day, &hour, &min, &sec, &n) != 6 || n != 19) {
+		throw std::invalid_argument("invalid RFC 3339 time");
+	}
+	s += n;
+
+	if (*s == '.') {
+		s++;
+		for (; *s >= '0' && *s <= '9'; s++) {
+			if (digits < 9) {
+				frac = frac * 10 + (*s - '0');
+				digits++;
+			}
+		}
+
+		if (digits == 0) {
+			throw std::invalid_argument("invalid RFC 3339 time");
+		}
+
+		for (; digits < 9; digits++) {
+			frac *= 10;
+		}
+	}
+
+	if (*s == 'Z' || *s == 'z') {
+		s++;
+	} else if (*s == '+' || *s == '-') {
+		int oh, om;
+		int sign = *s == '-' ? -1 : 1;
+
+		if (std::sscanf(s + 1, "%2d:%2d%n", &oh, &om, &n) != 2 || n != 5) {
+			throw std::invalid_argument("invalid RFC 3339 time");
+		}
+
+		offset = sign * (oh * 3600 + om * 60);
+		s += 6;
+	} else {
+		throw std::invalid_argument("invalid RFC 3339 time");
+	}
+
+	if (*s != '\0') {
+		throw std::invalid_argument("invalid RFC 3339 time");
+	}
+
+	if (year == 1 && mon == 1 && day == 1 && hour == 0 && min == 0 && sec == 0 && frac == 0 && offset == 0) {
+		v = time_point{};
+		return;
+	}
+
+	std::int64_t secs = days_from_civil(year, (unsigned)mon, (unsigned)day) * 86400 + hour * 3600 + min * 60 + sec - offset;
+	v = time_point{std::chrono::nanoseconds{secs * 1000000000 + frac}};
+}
+
+template <typename T> void from_json_quoted(const nlohmann::json &j, T &v)
+{
+	if (j.is_null()) {
+		return;
+	}
+
+	const std::string &s = j.get_ref<const std::string &>();
+	size_t end = 0;
+	if constexpr (std::is_same_v<T, bool>) {
+		if (s != "true" && s != "false") {
+			throw std::invalid_argument("invalid quoted bool");
+		}
+		v = s == "true";
+		end = s.size();
+	} else if constexpr (std::is_floating_point_v<T>) {
+		v = (T)std::stod(s, &end);
+	} else if constexpr (std::is_signed_v<T>) {
+		v = (T)std::stoll(s, &end);
+	} else {
+		v = (T)std::stoull(s, &end);
+	}
+
+	if (end != s.size()) {
+		throw std::invalid_argument("invalid quoted value");
+	}
+}
+
+template <typename T> void from_json_quoted(const nlohmann::json &j, std::optional<T> &v)
+{
+	if (j.is_null()) {
+		v.reset();
+		return;
+	}
+
+	v.emplace();
+	from_json_quoted(j, *v);
+}
+
+} // namespace gsc
+
+#endif
+`
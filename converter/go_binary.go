@@ -0,0 +1,356 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/structtag"
+)
+
+// GoBinaryConverter generates MarshalBinary and UnmarshalBinary methods for
+// the go structs using the same encoding as CBinaryConverter
+type GoBinaryConverter struct {
+	BigEndian bool
+
+	warned map[string]bool
+}
+
+func (g *GoBinaryConverter) GetIdent(s string) string {
+	return s
+}
+
+var GoValidNameRegexp = regexp.MustCompile(`(?m)^[\pL_][\pL\pN_]*$`)
+
+func (g *GoBinaryConverter) ValidName(n string) bool {
+	return GoValidNameRegexp.MatchString(n)
+}
+
+func (g *GoBinaryConverter) GetTypeFromTags(tags *structtag.Tags) (StructMemberType, bool) {
+	return StructMemberType{}, false
+}
+
+func (g *GoBinaryConverter) FileExtension() string {
+	return "go"
+}
+
+const goBinaryHelpers = `type gscWriter struct {
+	b   []byte
+	err error
+}
+
+func (w *gscWriter) uint(v uint64, size int) {
+	for i := 0; i < size; i++ {
+		w.b = append(w.b, byte(v>>(8*%s)))
+	}
+}
+
+func (w *gscWriter) bool(v bool) {
+	if v {
+		w.uint(1, 1)
+	} else {
+		w.uint(0, 1)
+	}
+}
+
+func (w *gscWriter) float32(v float32) {
+	w.uint(uint64(math.Float32bits(v)), 4)
+}
+
+func (w *gscWriter) float64(v float64) {
+	w.uint(math.Float64bits(v), 8)
+}
+
+func (w *gscWriter) time(v time.Time) {
+	var n int64
+	if !v.IsZero() {
+		n = v.UnixNano()
+	}
+	w.uint(uint64(n), 8)
+}
+
+func (w *gscWriter) count(n int, max int, name string) {
+	if (max >= 0 && n > max) || uint64(n) > math.MaxUint32 {
+		if w.err == nil {
+			w.err = fmt.Errorf("%%s: length %%d exceeds %%d", name, n, max)
+		}
+	}
+	w.uint(uint64(n), 4)
+}
+
+func (w *gscWriter) string(v string, max int, name string) {
+	w.count(len(v), max, name)
+	w.b = append(w.b, v...)
+}
+
+func (w *gscWriter) bytes(v []byte, max int, name string) {
+	w.count(len(v), max, name)
+	w.b = append(w.b, v...)
+}
+
+type gscReader struct {
+	b   []byte
+	err error
+}
+
+func (r *gscReader) uint(size int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	if len(r.b) < size {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+
+	var v uint64
+	for i := 0; i < size; i++ {
+		v |= uint64(r.b[i]) << (8 * %s)
+	}
+	r.b = r.b[size:]
+
+	return v
+}
+
+func (r *gscReader) bool() bool {
+	return r.uint(1) != 0
+}
+
+func (r *gscReader) float32() float32 {
+	return math.Float32frombits(uint32(r.uint(4)))
+}
+
+func (r *gscReader) float64() float64 {
+	return math.Float64frombits(r.uint(8))
+}
+
+func (r *gscReader) time() time.Time {
+	n := int64(r.uint(8))
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// count reads a length, every element takes at least minSize bytes
+func (r *gscReader) count(max int, minSize int, name string) int {
+	n := int(r.uint(4))
+	if r.err != nil {
+		return 0
+	}
+
+	if max >= 0 && n > max {
+		r.err = fmt.Errorf("%%s: length %%d exceeds %%d", name, n, max)
+		return 0
+	}
+
+	if minSize > 0 && n > len(r.b)/minSize {
+		r.err = io.ErrUnexpectedEOF
+		return 0
+	}
+
+	return n
+}
+
+func (r *gscReader) string(max int, name string) string {
+	n := r.count(max, 1, name)
+	v := string(r.b[:n])
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *gscReader) bytes(max int, name string) []byte {
+	n := r.count(max, 1, name)
+	if n == 0 {
+		return nil
+	}
+	v := make([]byte, n)
+	copy(v, r.b)
+	r.b = r.b[n:]
+	return v
+}
+`
+
+func (g *GoBinaryConverter) writeValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, name string, bt *ValueType) {
+	switch bt.Kind {
+	case KindBool:
+		w.WriteString(fmt.Sprintf("%sw.bool(%s)\n", indent, expr))
+	case KindInt, KindUint:
+		w.WriteString(fmt.Sprintf("%sw.uint(uint64(%s), %d)\n", indent, expr, bt.Size))
+	case KindFloat:
+		w.WriteString(fmt.Sprintf("%sw.float%d(%s)\n", indent, bt.Size*8, expr))
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%sw.time(%s)\n", indent, expr))
+	case KindString:
+		method := "string"
+		if bt.GoType == "[]byte" {
+			method = "bytes"
+		}
+		w.WriteString(fmt.Sprintf("%sw.%s(%s, %d, %q)\n", indent, method, expr, bt.Fixed-1, name))
+	case KindStruct:
+		if bt.Struct.Anonymous {
+			g.writeMembers(w, indent, inspecter, expr, *bt.Struct)
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s.writeBinary(w)\n", indent, expr))
+		}
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sw.bool(%s != nil)\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, expr))
+		g.writeValue(w, indent+inspecter.Indent, inspecter, fmt.Sprintf("(*%s)", expr), name, bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		max := -1
+		if bt.Fixed > 0 {
+			max = bt.Fixed
+		}
+		w.WriteString(fmt.Sprintf("%sw.count(len(%s), %d, %q)\n", indent, expr, max, name))
+		w.WriteString(fmt.Sprintf("%sfor i := range %s {\n", indent, expr))
+		g.writeValue(w, indent+inspecter.Indent, inspecter, expr+"[i]", name, bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (g *GoBinaryConverter) readValue(w *strings.Builder, indent string, inspecter *Inspecter, expr string, name string, bt *ValueType) {
+	switch bt.Kind {
+	case KindBool:
+		w.WriteString(fmt.Sprintf("%s%s = r.bool()\n", indent, expr))
+	case KindInt, KindUint:
+		w.WriteString(fmt.Sprintf("%s%s = %s(r.uint(%d))\n", indent, expr, bt.GoType, bt.Size))
+	case KindFloat:
+		w.WriteString(fmt.Sprintf("%s%s = r.float%d()\n", indent, expr, bt.Size*8))
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%s%s = r.time()\n", indent, expr))
+	case KindString:
+		method := "string"
+		if bt.GoType == "[]byte" {
+			method = "bytes"
+		}
+		w.WriteString(fmt.Sprintf("%s%s = r.%s(%d, %q)\n", indent, expr, method, bt.Fixed-1, name))
+	case KindStruct:
+		if bt.Struct.Anonymous {
+			g.readMembers(w, indent, inspecter, expr, *bt.Struct)
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s.readBinary(r)\n", indent, expr))
+		}
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%s%s = nil\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sif r.bool() {\n", indent))
+		w.WriteString(fmt.Sprintf("%s%s%s = new(%s)\n", indent, inspecter.Indent, expr, bt.Elem.GoType))
+		g.readValue(w, indent+inspecter.Indent, inspecter, fmt.Sprintf("(*%s)", expr), name, bt.Elem)
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		max := -1
+		if bt.Fixed > 0 {
+			max = bt.Fixed
+		}
+		w.WriteString(fmt.Sprintf("%s%s = nil\n", indent, expr))
+		w.WriteString(fmt.Sprintf("%sif n := r.count(%d, %d, %q); n > 0 {\n", indent, max, inspecter.BinaryMinSize(bt.Elem), name))
+		w.WriteString(fmt.Sprintf("%s%s%s = make(%s, n)\n", indent, inspecter.Indent, expr, bt.GoType))
+		w.WriteString(fmt.Sprintf("%s%sfor i := range %s {\n", indent, inspecter.Indent, expr))
+		g.readValue(w, indent+inspecter.Indent+inspecter.Indent, inspecter, expr+"[i]", name, bt.Elem)
+		w.WriteString(fmt.Sprintf("%s%s}\n", indent, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (g *GoBinaryConverter) binaryMembers(inspecter *Inspecter, goStruct Struct) ([]StructMember, []*ValueType) {
+	var members []StructMember
+	var types []*ValueType
+
+	for _, member := range goStruct.Members {
+		bt, err := inspecter.BinaryMemberType(member)
+		if err != nil {
+			warning := fmt.Sprintf("WARNING! %s, skipping %s.%s", err, goStruct.GoName, member.Name)
+			if !g.warned[warning] {
+				fmt.Fprintln(os.Stderr, warning)
+				g.warned[warning] = true
+			}
+			continue
+		}
+
+		members = append(members, member)
+		types = append(types, bt)
+	}
+
+	return members, types
+}
+
+func (g *GoBinaryConverter) writeMembers(w *strings.Builder, indent string, inspecter *Inspecter, expr string, goStruct Struct) {
+	members, types := g.binaryMembers(inspecter, goStruct)
+	for i, member := range members {
+		g.writeValue(w, indent, inspecter, expr+"."+member.Name, goStruct.GoName+"."+member.Name, types[i])
+	}
+}
+
+func (g *GoBinaryConverter) readMembers(w *strings.Builder, indent string, inspecter *Inspecter, expr string, goStruct Struct) {
+	members, types := g.binaryMembers(inspecter, goStruct)
+	for i, member := range members {
+		g.readValue(w, indent, inspecter, expr+"."+member.Name, goStruct.GoName+"."+member.Name, types[i])
+	}
+}
+
+func (g *GoBinaryConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	g.warned = make(map[string]bool)
+
+	var pkg string
+	for _, goStruct := range inspecter.Structs {
+		if pkg == "" {
+			pkg = goStruct.Package
+		} else if goStruct.Package != pkg {
+			return fmt.Errorf("all structs must be in the same package, found %s and %s", pkg, goStruct.Package)
+		}
+	}
+
+	if pkg == "" {
+		return errors.New("unable to determine the go package")
+	}
+
+	w.WriteString("// Code generated by go-struct-convert. DO NOT EDIT.\n\n")
+	w.WriteString(fmt.Sprintf("package %s\n\n", pkg))
+	w.WriteString("import (\n")
+	for _, imp := range []string{"fmt", "io", "math", "time"} {
+		w.WriteString(fmt.Sprintf("%s%q\n", inspecter.Indent, imp))
+	}
+	w.WriteString(")\n\n")
+
+	shift := "i"
+	if g.BigEndian {
+		shift = "(size - 1 - i)"
+	}
+	w.WriteString(fmt.Sprintf(goBinaryHelpers, shift, shift))
+
+	for _, goStruct := range inspecter.Structs {
+		if goStruct.Anonymous {
+			continue
+		}
+
+		name := goStruct.GoName
+
+		w.WriteString(fmt.Sprintf("\nfunc (x *%s) writeBinary(w *gscWriter) {\n", name))
+		g.writeMembers(w, inspecter.Indent, inspecter, "x", goStruct)
+		w.WriteString("}\n")
+
+		w.WriteString(fmt.Sprintf("\nfunc (x *%s) readBinary(r *gscReader) {\n", name))
+		g.readMembers(w, inspecter.Indent, inspecter, "x", goStruct)
+		w.WriteString("}\n")
+
+		w.WriteString(fmt.Sprintf("\nfunc (x %s) MarshalBinary() ([]byte, error) {\n", name))
+		w.WriteString(fmt.Sprintf("%sw := &gscWriter{}\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sx.writeBinary(w)\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sreturn w.b, w.err\n", inspecter.Indent))
+		w.WriteString("}\n")
+
+		w.WriteString(fmt.Sprintf("\nfunc (x *%s) UnmarshalBinary(data []byte) error {\n", name))
+		w.WriteString(fmt.Sprintf("%sr := &gscReader{b: data}\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sx.readBinary(r)\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sif r.err == nil && len(r.b) != 0 {\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s%sr.err = fmt.Errorf(\"%s: %%d unexpected trailing bytes\", len(r.b))\n", inspecter.Indent, inspecter.Indent, name))
+		w.WriteString(fmt.Sprintf("%s}\n", inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%sreturn r.err\n", inspecter.Indent))
+		w.WriteString("}\n")
+	}
+
+	return nil
+}
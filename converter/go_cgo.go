@@ -0,0 +1,507 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/structtag"
+)
+
+// GoCgoConverter generates go functions converting the go structs to and
+// from the c structs declared by the c header, for use with cgo. toCX
+// allocates strings, pointers, slices and maps with malloc, freeCX releases
+// them again.
+type GoCgoConverter struct {
+	Header string // the generated header to include
+
+	warned map[string]bool
+}
+
+func (g *GoCgoConverter) GetIdent(s string) string {
+	return (&CConverter{}).GetIdent(s)
+}
+
+func (g *GoCgoConverter) ValidName(n string) bool {
+	return GoValidNameRegexp.MatchString(n)
+}
+
+func (g *GoCgoConverter) GetTypeFromTags(tags *structtag.Tags) (StructMemberType, bool) {
+	return (&CConverter{}).GetTypeFromTags(tags)
+}
+
+func (g *GoCgoConverter) FileExtension() string {
+	return "go"
+}
+
+const goCgoHelpers = `func gscCgoBool(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func gscCgoTime(v time.Time) int64 {
+	if v.IsZero() {
+		return 0
+	}
+	return v.UnixNano()
+}
+
+func gscCgoFromTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// gscCgoPutString copies s into a fixed size c array, truncating it to leave room for the terminating NUL
+func gscCgoPutString(dst []C.char, s string) {
+	if len(dst) == 0 {
+		return
+	}
+
+	n := len(s)
+	if n > len(dst)-1 {
+		n = len(dst) - 1
+	}
+
+	for i := 0; i < n; i++ {
+		dst[i] = C.char(s[i])
+	}
+	dst[n] = 0
+}
+
+// gscCgoGetString reads a fixed size c array that may not be NUL terminated
+func gscCgoGetString(src []C.char) string {
+	b := make([]byte, 0, len(src))
+	for _, c := range src {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
+
+func gscCgoBytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return []byte(s)
+}
+`
+
+var cgoTypeNames = map[string]string{
+	"char":               "C.char",
+	"signed char":        "C.schar",
+	"unsigned char":      "C.uchar",
+	"short":              "C.short",
+	"unsigned short":     "C.ushort",
+	"int":                "C.int",
+	"unsigned int":       "C.uint",
+	"long":               "C.long",
+	"unsigned long":      "C.ulong",
+	"long long":          "C.longlong",
+	"unsigned long long": "C.ulonglong",
+	"float":              "C.float",
+	"double":             "C.double",
+	"void":               "byte",
+}
+
+// cgoType is the go name cgo gives a c type, e.g. `unsigned int` is C.uint and `char *` is *C.char
+func cgoType(t StructMemberType) string {
+	base := strings.TrimSpace(t.Prefix + t.Value)
+	if strings.HasSuffix(base, "*") {
+		return "*" + cgoType(StructMemberType{Value: strings.TrimSuffix(base, "*")})
+	}
+
+	if name, ok := cgoTypeNames[base]; ok {
+		return name
+	}
+
+	return "C." + base
+}
+
+// cgoNumeric is whether the c type of a go bool is an integer rather than a c bool
+func cgoNumeric(t StructMemberType) bool {
+	base := strings.TrimSpace(t.Prefix + t.Value)
+	_, ok := cScalarSizes[base]
+	return ok && base != "bool" && base != "_Bool"
+}
+
+// cgoNeedsFree is whether converting a value to c allocates memory
+func cgoNeedsFree(vt *ValueType) bool {
+	switch vt.Kind {
+	case KindString:
+		return vt.Fixed == 0
+	case KindStruct, KindPointer, KindMap:
+		return true
+	case KindSlice:
+		return vt.Fixed == 0 || cgoNeedsFree(vt.Elem)
+	}
+
+	return false
+}
+
+func (g *GoCgoConverter) cgoMembers(inspecter *Inspecter, goStruct Struct) ([]StructMember, []*ValueType) {
+	var members []StructMember
+	var types []*ValueType
+
+	for _, member := range goStruct.Members {
+		vt, err := inspecter.MemberValueType(member)
+		if err == nil {
+			var bits int
+			bits, err = memberBits(member)
+			if err == nil && bits > 0 {
+				err = errors.New("bitfields are not visible to cgo")
+			}
+		}
+
+		if err != nil {
+			warning := fmt.Sprintf("WARNING! %s, skipping %s.%s", err, goStruct.GoName, member.Name)
+			if !g.warned[warning] {
+				fmt.Fprintln(os.Stderr, warning)
+				g.warned[warning] = true
+			}
+			continue
+		}
+
+		members = append(members, member)
+		types = append(types, vt)
+	}
+
+	return members, types
+}
+
+// toC writes the statements converting the go value src into the c value dst,
+// t is the c type of the value and entry the c struct of map entries
+func (g *GoCgoConverter) toC(w *strings.Builder, indent string, inspecter *Inspecter, dst string, src string, vt *ValueType, t StructMemberType, entry string, depth int) {
+	inner := indent + inspecter.Indent
+	leaf := cgoType(StructMemberType{Prefix: t.Prefix, Value: t.Value})
+
+	switch vt.Kind {
+	case KindBool:
+		if cgoNumeric(t) {
+			w.WriteString(fmt.Sprintf("%s%s = %s(gscCgoBool(%s))\n", indent, dst, leaf, src))
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = %s(%s)\n", indent, dst, leaf, src))
+		}
+	case KindInt, KindUint, KindFloat:
+		w.WriteString(fmt.Sprintf("%s%s = %s(%s)\n", indent, dst, leaf, src))
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%s%s = %s(gscCgoTime(%s))\n", indent, dst, leaf, src))
+	case KindString:
+		value := src
+		if vt.GoType == "[]byte" {
+			value = fmt.Sprintf("string(%s)", src)
+		}
+
+		if vt.Fixed > 0 {
+			w.WriteString(fmt.Sprintf("%sgscCgoPutString((*[%d]C.char)(unsafe.Pointer(&%s))[:], %s)\n", indent, vt.Fixed, dst, value))
+			return
+		}
+
+		cstring := fmt.Sprintf("C.CString(%s)", value)
+		if leaf != "*C.char" {
+			cstring = fmt.Sprintf("(%s)(unsafe.Pointer(%s))", leaf, cstring)
+		}
+
+		if vt.GoType == "[]byte" {
+			// nil stays NULL
+			w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, src))
+			w.WriteString(fmt.Sprintf("%s%s = %s\n", inner, dst, cstring))
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = %s\n", indent, dst, cstring))
+		}
+	case KindStruct:
+		if vt.Struct.Anonymous {
+			g.membersToC(w, indent, inspecter, dst, src, *vt.Struct, depth)
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = toC%s(&%s)\n", indent, dst, vt.Struct.GoName, src))
+		}
+	case KindPointer:
+		p := fmt.Sprintf("p%d", depth)
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s := (*%s)(C.calloc(1, C.size_t(unsafe.Sizeof(*%s))))\n", inner, p, leaf, dst))
+		g.toC(w, inner, inspecter, fmt.Sprintf("(*%s)", p), fmt.Sprintf("(*%s)", src), vt.Elem, t, "", depth+1)
+		w.WriteString(fmt.Sprintf("%s%s = %s\n", inner, dst, p))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		index := fmt.Sprintf("i%d", depth)
+		if vt.Fixed > 0 {
+			a := fmt.Sprintf("(*[%d]%s)(unsafe.Pointer(&%s))", vt.Fixed, leaf, dst)
+			w.WriteString(fmt.Sprintf("%sfor %s := 0; %s < len(%s) && %s < %d; %s++ {\n", indent, index, index, src, index, vt.Fixed, index))
+			g.toC(w, inner, inspecter, fmt.Sprintf("%s[%s]", a, index), fmt.Sprintf("%s[%s]", src, index), vt.Elem, t, "", depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+			return
+		}
+
+		s := fmt.Sprintf("s%d", depth)
+		w.WriteString(fmt.Sprintf("%sif len(%s) > 0 {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice((*%s)(C.calloc(C.size_t(len(%s)), C.size_t(unsafe.Sizeof(*%s)))), len(%s))\n", inner, s, leaf, src, dst, src))
+		w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", inner, index, src))
+		g.toC(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s]", s, index), fmt.Sprintf("%s[%s]", src, index), vt.Elem, t, "", depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s%s = &%s[0]\n", inner, dst, s))
+		w.WriteString(fmt.Sprintf("%s%s_len = C.size_t(len(%s))\n", inner, dst, src))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindMap:
+		e := fmt.Sprintf("e%d", depth)
+		index := fmt.Sprintf("i%d", depth)
+		key := fmt.Sprintf("k%d", depth)
+		value := fmt.Sprintf("v%d", depth)
+		w.WriteString(fmt.Sprintf("%sif len(%s) > 0 {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice((*C.%s)(C.calloc(C.size_t(len(%s)), C.size_t(unsafe.Sizeof(*%s)))), len(%s))\n", inner, e, entry, src, dst, src))
+		w.WriteString(fmt.Sprintf("%s%s := 0\n", inner, index))
+		w.WriteString(fmt.Sprintf("%sfor %s, %s := range %s {\n", inner, key, value, src))
+		g.toC(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s].key", e, index), key, vt.Key, *t.MapKey, "", depth+1)
+		g.toC(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s].value", e, index), value, vt.Elem, *t.MapVal, "", depth+1)
+		w.WriteString(fmt.Sprintf("%s%s%s++\n", inner, inspecter.Indent, index))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s%s = &%s[0]\n", inner, dst, e))
+		w.WriteString(fmt.Sprintf("%s%s_len = C.size_t(len(%s))\n", inner, dst, src))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+// fromC writes the statements converting the c value src into the go value dst
+func (g *GoCgoConverter) fromC(w *strings.Builder, indent string, inspecter *Inspecter, dst string, src string, vt *ValueType, t StructMemberType, depth int) {
+	inner := indent + inspecter.Indent
+	leaf := cgoType(StructMemberType{Prefix: t.Prefix, Value: t.Value})
+
+	switch vt.Kind {
+	case KindBool:
+		if cgoNumeric(t) {
+			w.WriteString(fmt.Sprintf("%s%s = %s != 0\n", indent, dst, src))
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = bool(%s)\n", indent, dst, src))
+		}
+	case KindInt, KindUint, KindFloat:
+		w.WriteString(fmt.Sprintf("%s%s = %s(%s)\n", indent, dst, vt.GoType, src))
+	case KindTime:
+		w.WriteString(fmt.Sprintf("%s%s = gscCgoFromTime(int64(%s))\n", indent, dst, src))
+	case KindString:
+		var value string
+		if vt.Fixed > 0 {
+			value = fmt.Sprintf("gscCgoGetString((*[%d]C.char)(unsafe.Pointer(&%s))[:])", vt.Fixed, src)
+		} else if leaf != "*C.char" {
+			value = fmt.Sprintf("C.GoString((*C.char)(unsafe.Pointer(%s)))", src)
+		} else {
+			value = fmt.Sprintf("C.GoString(%s)", src)
+		}
+
+		if vt.GoType == "[]byte" {
+			value = fmt.Sprintf("gscCgoBytes(%s)", value)
+		}
+
+		w.WriteString(fmt.Sprintf("%s%s = %s\n", indent, dst, value))
+	case KindStruct:
+		if vt.Struct.Anonymous {
+			g.membersFromC(w, indent, inspecter, dst, src, *vt.Struct, depth)
+		} else {
+			w.WriteString(fmt.Sprintf("%s%s = fromC%s(&%s)\n", indent, dst, vt.Struct.GoName, src))
+		}
+	case KindPointer:
+		p := fmt.Sprintf("p%d", depth)
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, src))
+		w.WriteString(fmt.Sprintf("%s%s := new(%s)\n", inner, p, vt.Elem.GoType))
+		g.fromC(w, inner, inspecter, fmt.Sprintf("(*%s)", p), fmt.Sprintf("(*%s)", src), vt.Elem, t, depth+1)
+		w.WriteString(fmt.Sprintf("%s%s = %s\n", inner, dst, p))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		index := fmt.Sprintf("i%d", depth)
+		if vt.Fixed > 0 {
+			a := fmt.Sprintf("(*[%d]%s)(unsafe.Pointer(&%s))", vt.Fixed, leaf, src)
+			w.WriteString(fmt.Sprintf("%s%s = make(%s, %d)\n", indent, dst, vt.GoType, vt.Fixed))
+			w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", indent, index, dst))
+			g.fromC(w, inner, inspecter, fmt.Sprintf("%s[%s]", dst, index), fmt.Sprintf("%s[%s]", a, index), vt.Elem, t, depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+			return
+		}
+
+		s := fmt.Sprintf("s%d", depth)
+		w.WriteString(fmt.Sprintf("%sif %s != nil && %s_len > 0 {\n", indent, src, src))
+		w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice(%s, %s_len)\n", inner, s, src, src))
+		w.WriteString(fmt.Sprintf("%s%s = make(%s, len(%s))\n", inner, dst, vt.GoType, s))
+		w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", inner, index, s))
+		g.fromC(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s]", dst, index), fmt.Sprintf("%s[%s]", s, index), vt.Elem, t, depth+1)
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindMap:
+		e := fmt.Sprintf("e%d", depth)
+		index := fmt.Sprintf("i%d", depth)
+		key := fmt.Sprintf("k%d", depth)
+		value := fmt.Sprintf("v%d", depth)
+		w.WriteString(fmt.Sprintf("%sif %s != nil && %s_len > 0 {\n", indent, src, src))
+		w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice(%s, %s_len)\n", inner, e, src, src))
+		w.WriteString(fmt.Sprintf("%s%s = make(%s, len(%s))\n", inner, dst, vt.GoType, e))
+		w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", inner, index, e))
+		w.WriteString(fmt.Sprintf("%s%svar %s %s\n", inner, inspecter.Indent, key, vt.Key.GoType))
+		w.WriteString(fmt.Sprintf("%s%svar %s %s\n", inner, inspecter.Indent, value, vt.Elem.GoType))
+		g.fromC(w, inner+inspecter.Indent, inspecter, key, fmt.Sprintf("%s[%s].key", e, index), vt.Key, *t.MapKey, depth+1)
+		g.fromC(w, inner+inspecter.Indent, inspecter, value, fmt.Sprintf("%s[%s].value", e, index), vt.Elem, *t.MapVal, depth+1)
+		w.WriteString(fmt.Sprintf("%s%s%s[%s] = %s\n", inner, inspecter.Indent, dst, key, value))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+// free writes the statements releasing the memory toC allocated for the c value expr
+func (g *GoCgoConverter) free(w *strings.Builder, indent string, inspecter *Inspecter, expr string, vt *ValueType, t StructMemberType, depth int) {
+	inner := indent + inspecter.Indent
+	leaf := cgoType(StructMemberType{Prefix: t.Prefix, Value: t.Value})
+
+	switch vt.Kind {
+	case KindString:
+		if vt.Fixed == 0 {
+			w.WriteString(fmt.Sprintf("%sC.free(unsafe.Pointer(%s))\n", indent, expr))
+		}
+	case KindStruct:
+		if vt.Struct.Anonymous {
+			g.freeMembers(w, indent, inspecter, expr, *vt.Struct, depth)
+		} else {
+			w.WriteString(fmt.Sprintf("%sfreeC%s(&%s)\n", indent, vt.Struct.GoName, expr))
+		}
+	case KindPointer:
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, expr))
+		if cgoNeedsFree(vt.Elem) {
+			g.free(w, inner, inspecter, fmt.Sprintf("(*%s)", expr), vt.Elem, t, depth+1)
+		}
+		w.WriteString(fmt.Sprintf("%sC.free(unsafe.Pointer(%s))\n", inner, expr))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindSlice:
+		index := fmt.Sprintf("i%d", depth)
+		if vt.Fixed > 0 {
+			if !cgoNeedsFree(vt.Elem) {
+				return
+			}
+
+			a := fmt.Sprintf("(*[%d]%s)(unsafe.Pointer(&%s))", vt.Fixed, leaf, expr)
+			w.WriteString(fmt.Sprintf("%sfor %s := 0; %s < %d; %s++ {\n", indent, index, index, vt.Fixed, index))
+			g.free(w, inner, inspecter, fmt.Sprintf("%s[%s]", a, index), vt.Elem, t, depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", indent))
+			return
+		}
+
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, expr))
+		if cgoNeedsFree(vt.Elem) {
+			s := fmt.Sprintf("s%d", depth)
+			w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice(%s, %s_len)\n", inner, s, expr, expr))
+			w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", inner, index, s))
+			g.free(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s]", s, index), vt.Elem, t, depth+1)
+			w.WriteString(fmt.Sprintf("%s}\n", inner))
+		}
+		w.WriteString(fmt.Sprintf("%sC.free(unsafe.Pointer(%s))\n", inner, expr))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	case KindMap:
+		w.WriteString(fmt.Sprintf("%sif %s != nil {\n", indent, expr))
+		if cgoNeedsFree(vt.Key) || cgoNeedsFree(vt.Elem) {
+			e := fmt.Sprintf("e%d", depth)
+			index := fmt.Sprintf("i%d", depth)
+			w.WriteString(fmt.Sprintf("%s%s := unsafe.Slice(%s, %s_len)\n", inner, e, expr, expr))
+			w.WriteString(fmt.Sprintf("%sfor %s := range %s {\n", inner, index, e))
+			if cgoNeedsFree(vt.Key) {
+				g.free(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s].key", e, index), vt.Key, *t.MapKey, depth+1)
+			}
+			if cgoNeedsFree(vt.Elem) {
+				g.free(w, inner+inspecter.Indent, inspecter, fmt.Sprintf("%s[%s].value", e, index), vt.Elem, *t.MapVal, depth+1)
+			}
+			w.WriteString(fmt.Sprintf("%s}\n", inner))
+		}
+		w.WriteString(fmt.Sprintf("%sC.free(unsafe.Pointer(%s))\n", inner, expr))
+		w.WriteString(fmt.Sprintf("%s}\n", indent))
+	}
+}
+
+func (g *GoCgoConverter) membersToC(w *strings.Builder, indent string, inspecter *Inspecter, dst string, src string, goStruct Struct, depth int) {
+	members, types := g.cgoMembers(inspecter, goStruct)
+	for i, member := range members {
+		g.toC(w, indent, inspecter, dst+"."+member.Name, src+"."+member.Name, types[i], member.Type, CMapEntryName(goStruct, member), depth)
+	}
+}
+
+func (g *GoCgoConverter) membersFromC(w *strings.Builder, indent string, inspecter *Inspecter, dst string, src string, goStruct Struct, depth int) {
+	members, types := g.cgoMembers(inspecter, goStruct)
+	for i, member := range members {
+		g.fromC(w, indent, inspecter, dst+"."+member.Name, src+"."+member.Name, types[i], member.Type, depth)
+	}
+}
+
+func (g *GoCgoConverter) freeMembers(w *strings.Builder, indent string, inspecter *Inspecter, expr string, goStruct Struct, depth int) {
+	members, types := g.cgoMembers(inspecter, goStruct)
+	for i, member := range members {
+		if cgoNeedsFree(types[i]) {
+			g.free(w, indent, inspecter, expr+"."+member.Name, types[i], member.Type, depth)
+		}
+	}
+}
+
+func (g *GoCgoConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	g.warned = make(map[string]bool)
+
+	var pkg string
+	for _, goStruct := range inspecter.Structs {
+		if pkg == "" {
+			pkg = goStruct.Package
+		} else if goStruct.Package != pkg {
+			return fmt.Errorf("all structs must be in the same package, found %s and %s", pkg, goStruct.Package)
+		}
+	}
+
+	if pkg == "" {
+		return errors.New("unable to determine the go package")
+	}
+
+	w.WriteString("// Code generated by go-struct-convert. DO NOT EDIT.\n\n")
+	w.WriteString(fmt.Sprintf("package %s\n\n", pkg))
+	w.WriteString("/*\n")
+	for _, include := range []string{"<stdbool.h>", "<stdint.h>", "<stdlib.h>"} {
+		w.WriteString(fmt.Sprintf("#include %s\n", include))
+	}
+	if g.Header != "" {
+		w.WriteString(fmt.Sprintf("#include \"%s\"\n", g.Header))
+	}
+	w.WriteString("*/\n")
+	w.WriteString("import \"C\"\n\n")
+	w.WriteString("import (\n")
+	for _, imp := range []string{"time", "unsafe"} {
+		w.WriteString(fmt.Sprintf("%s%q\n", inspecter.Indent, imp))
+	}
+	w.WriteString(")\n\n")
+
+	w.WriteString(goCgoHelpers)
+
+	for _, goStruct := range inspecter.Structs {
+		if goStruct.Anonymous {
+			continue
+		}
+
+		name := goStruct.GoName
+		indent := inspecter.Indent
+
+		w.WriteString(fmt.Sprintf("\n// toC%s converts x to a C.%s, release it with freeC%s\n", name, goStruct.Name, name))
+		w.WriteString(fmt.Sprintf("func toC%s(x *%s) C.%s {\n", name, name, goStruct.Name))
+		w.WriteString(fmt.Sprintf("%svar c C.%s\n", indent, goStruct.Name))
+		g.membersToC(w, indent, inspecter, "c", "x", goStruct, 0)
+		w.WriteString(fmt.Sprintf("%sreturn c\n", indent))
+		w.WriteString("}\n")
+
+		w.WriteString(fmt.Sprintf("\n// fromC%s converts c to a %s, copying everything it points to\n", name, name))
+		w.WriteString(fmt.Sprintf("func fromC%s(c *C.%s) %s {\n", name, goStruct.Name, name))
+		w.WriteString(fmt.Sprintf("%svar x %s\n", indent, name))
+		g.membersFromC(w, indent, inspecter, "x", "c", goStruct, 0)
+		w.WriteString(fmt.Sprintf("%sreturn x\n", indent))
+		w.WriteString("}\n")
+
+		w.WriteString(fmt.Sprintf("\n// freeC%s releases the memory held by c and zeroes it\n", name))
+		w.WriteString(fmt.Sprintf("func freeC%s(c *C.%s) {\n", name, goStruct.Name))
+		g.freeMembers(w, indent, inspecter, "c", goStruct, 0)
+		w.WriteString(fmt.Sprintf("%s*c = C.%s{}\n", indent, goStruct.Name))
+		w.WriteString("}\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/structtag"
+)
+
+// TypeID is the stable numeric id of a named struct, used to tell messages apart
+type TypeID struct {
+	Struct *Struct
+	ID     uint32
+}
+
+// maxTypeID keeps ids within a c enum
+const maxTypeID = 0x7fffffff
+
+// TypeIDs returns the ids of the named structs, taken from a `//gsc:id=N`
+// directive or a hash of the go name. Two structs with the same id are an error.
+func (inspecter *Inspecter) TypeIDs() ([]TypeID, error) {
+	var ids []TypeID
+	seen := make(map[uint32]*Struct)
+
+	for i := range inspecter.Structs {
+		s := &inspecter.Structs[i]
+		if s.Anonymous {
+			continue
+		}
+
+		id, err := structTypeID(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if other, ok := seen[id]; ok {
+			return nil, fmt.Errorf("%s and %s share the type id %d, set one with a //gsc:id=N directive", other.GoName, s.GoName, id)
+		}
+		seen[id] = s
+
+		ids = append(ids, TypeID{Struct: s, ID: id})
+	}
+
+	return ids, nil
+}
+
+func structTypeID(s *Struct) (uint32, error) {
+	if value, ok := s.Directives["id"]; ok {
+		id, err := strconv.ParseUint(value, 0, 32)
+		if err != nil || id == 0 || id > maxTypeID {
+			return 0, fmt.Errorf("%s: invalid value for gsc:id: %q, expected 1 to %d", s.GoName, value, maxTypeID)
+		}
+
+		return uint32(id), nil
+	}
+
+	// FNV-1a keeps the id stable as long as the go name does
+	h := fnv.New32a()
+	h.Write([]byte(s.GoName))
+
+	id := h.Sum32() & maxTypeID
+	if id == 0 {
+		id = 1
+	}
+
+	return id, nil
+}
+
+// TypeIDConstant is the name of the c and typescript constant holding the id of a struct
+func TypeIDConstant(s *Struct) string {
+	return ConstantCase(s.Name) + "_TYPE_ID"
+}
+
+// GoTypeIDConverter generates the type id constants and a TypeID method for
+// the go structs, matching the c and typescript type ids
+type GoTypeIDConverter struct{}
+
+func (g *GoTypeIDConverter) GetIdent(s string) string {
+	return s
+}
+
+func (g *GoTypeIDConverter) ValidName(n string) bool {
+	return GoValidNameRegexp.MatchString(n)
+}
+
+func (g *GoTypeIDConverter) GetTypeFromTags(tags *structtag.Tags) (StructMemberType, bool) {
+	return StructMemberType{}, false
+}
+
+func (g *GoTypeIDConverter) FileExtension() string {
+	return "go"
+}
+
+func (g *GoTypeIDConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
+	var pkg string
+	for _, goStruct := range inspecter.Structs {
+		if pkg == "" {
+			pkg = goStruct.Package
+		} else if goStruct.Package != pkg {
+			return fmt.Errorf("all structs must be in the same package, found %s and %s", pkg, goStruct.Package)
+		}
+	}
+
+	if pkg == "" {
+		return errors.New("unable to determine the go package")
+	}
+
+	ids, err := inspecter.TypeIDs()
+	if err != nil {
+		return err
+	}
+
+	w.WriteString("// Code generated by go-struct-convert. DO NOT EDIT.\n\n")
+	w.WriteString(fmt.Sprintf("package %s\n", pkg))
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, id := range ids {
+		name := id.Struct.GoName
+		w.WriteString(fmt.Sprintf("\n// %sTypeID matches the c and typescript %s constant\n", name, TypeIDConstant(id.Struct)))
+		w.WriteString(fmt.Sprintf("const %sTypeID uint32 = %d\n", name, id.ID))
+
+		w.WriteString(fmt.Sprintf("\n// TypeID returns the id identifying %s messages\n", name))
+		w.WriteString(fmt.Sprintf("func (%s) TypeID() uint32 {\n", name))
+		w.WriteString(fmt.Sprintf("%sreturn %sTypeID\n", inspecter.Indent, name))
+		w.WriteString("}\n")
+	}
+
+	return nil
+}
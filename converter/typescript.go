@@ -2,15 +2,64 @@ package converter
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/structtag"
 )
 
 type TypescriptConverter struct {
-	Namespace string
+	Namespace   string
+	Module      bool   // export the declarations at the top level of an es module instead of declaring globals
+	Style       string // "interface" (the default) or "type" to declare type aliases
+	Declaration bool   // generate a .d.ts declaration file
+	Constants   bool   // declare consts for the array capacities and validate bounds of members
+	TypeIDs     bool   // declare consts for the type ids of the structs
+
+	// Nullability is "pointer" to make pointers optional or "json" to follow
+	// encoding/json, where nil pointers are null and only omitempty members
+	// can be missing. Interfaces default to pointer and zod schemas to json.
+	Nullability    string
+	NullableSlices bool // slices and maps can be null, as nil ones are encoded
+
+	Zod    bool // generate zod schemas and the types they infer instead of interfaces
+	Guards bool // generate isX and assertX functions checking values at runtime
+
+	// Int64 is the typescript type of int64 and uint64 values: "number" (the
+	// default) loses precision above 2^53, "bigint" keeps it and "string"
+	// keeps the members with the ,string json option as they are encoded
+	Int64 string
+
+	// Time is the typescript type of time.Time: "string" (the default of
+	// interfaces and zod schemas), "Date" (the default of classes), the
+	// branded "ISODateString" or a user type such as moment.Moment, decoded
+	// with TimeParse and encoded with TimeFormat or its toJSON method
+	Time       string
+	TimeParse  string
+	TimeFormat string
+
+	// Duration is the typescript type of time.Duration, the nanoseconds as a
+	// number by default or a user type converted with DurationParse and DurationFormat
+	Duration       string
+	DurationParse  string
+	DurationFormat string
+
+	// Split is "package" or "file" to generate an es module per go package
+	// or input file and an index module re-exporting them, see BuildFiles
+	Split string
+
+	// Brands declares named scalar types such as type UserID int64 as
+	// branded types with a constructor function instead of plain aliases
+	Brands bool
+
+	// Factories generates a module of makeX(overrides) functions filling
+	// every struct with deterministic test data, see factories
+	Factories bool
+
+	module string // the module BuildFiles is building, empty for a single file
 }
 
 func (ts *TypescriptConverter) GetIdent(s string) string {
@@ -25,8 +74,9 @@ func (ts *TypescriptConverter) GetIdent(s string) string {
 		"complex64", "complex128":
 		return "number"
 	case "time.Time":
-		// TODO not sure here
-		return "number"
+		return ts.timeType()
+	case "time.Duration":
+		return ts.durationType()
 	case "decimal.Decimal":
 		return "number"
 	}
@@ -64,19 +114,195 @@ func TypescriptValidJSName(n string) bool {
 }
 
 func (ts *TypescriptConverter) FileExtension() string {
+	if ts.Declaration {
+		return "d.ts"
+	}
+
 	return "ts"
 }
 
+// exported is whether declarations are exported from a module or namespace rather than global
+func (ts *TypescriptConverter) exported() bool {
+	return ts.Module || ts.Zod || ts.Namespace != ""
+}
+
+// constant writes a const declaration matching the output mode
+func (ts *TypescriptConverter) constant(w *strings.Builder, indent string, name string, value string) {
+	w.WriteString(indent)
+	if ts.exported() {
+		w.WriteString("export ")
+	}
+	if ts.Declaration && ts.Namespace == "" {
+		w.WriteString("declare ")
+	}
+	w.WriteString(fmt.Sprintf("const %s = %s;\n", name, value))
+}
+
+// tagBool returns the value of a `tag:"true"` or `tag:"false"` override
+func tagBool(member StructMember, name string) (bool, bool, error) {
+	if member.Tags == nil {
+		return false, false, nil
+	}
+
+	tag, err := member.Tags.Get(name)
+	if err != nil {
+		return false, false, nil
+	}
+
+	value, err := strconv.ParseBool(tag.Name)
+	if err != nil {
+		return false, false, fmt.Errorf("%s: %s must be true or false, got %q", member.Name, name, tag.Name)
+	}
+
+	return value, true, nil
+}
+
+// nullability returns whether a member may be missing and whether it may be
+// null, `tsoptional:"false"` and `tsnullable:"true"` tags override the model
+func (ts *TypescriptConverter) nullability(member StructMember) (bool, bool, error) {
+	var optional, nullable bool
+
+	switch ts.Nullability {
+	case "", "pointer":
+		optional = member.Type.IsPointer
+	case "json":
+		optional = member.JSONOmitEmpty
+		nullable = member.Type.IsPointer
+	default:
+		return false, false, fmt.Errorf("unknown nullability %q, expected pointer or json", ts.Nullability)
+	}
+
+	if ts.NullableSlices && (member.Type.IsArray || member.Type.IsMap) {
+		nullable = true
+	}
+
+	if value, ok, err := tagBool(member, "tsoptional"); err != nil {
+		return false, false, err
+	} else if ok {
+		optional = value
+	}
+
+	if value, ok, err := tagBool(member, "tsnullable"); err != nil {
+		return false, false, err
+	} else if ok {
+		nullable = value
+	}
+
+	return optional, nullable, nil
+}
+
+// memberType returns the typescript type of a member as the interfaces declare it
+func (ts *TypescriptConverter) memberType(member StructMember) string {
+	if member.Type.IsMap {
+		return fmt.Sprintf("{ [key: %s%s%s]: %s%s%s}", member.Type.MapKey.Prefix, member.Type.MapKey.Value, member.Type.MapKey.Suffix, member.Type.MapVal.Prefix, member.Type.MapVal.Value, member.Type.MapVal.Suffix)
+	}
+
+	if len(member.OneOf) > 0 {
+		// a discriminated union of the declared variants
+		var variants []string
+		for _, variant := range member.OneOf {
+			variants = append(variants, fmt.Sprintf("({ kind: \"%s\" } & %s)", variant.Name, variant.Type))
+		}
+
+		return strings.Join(variants, " | ")
+	}
+
+	t := member.Type.Prefix + member.Type.Value + member.Type.Suffix
+	if member.Type.IsArray {
+		t += "[]"
+	}
+
+	return t
+}
+
+// memberConstants writes the consts of a struct, followed by a blank line when there are any
+func (ts *TypescriptConverter) memberConstants(w *strings.Builder, indent string, s Struct) {
+	defined := false
+	for _, member := range s.Members {
+		for _, constant := range MemberConstants(s, member) {
+			ts.constant(w, indent, constant.Name, constant.Value)
+			defined = true
+		}
+	}
+
+	if defined {
+		w.WriteString("\n")
+	}
+}
+
+func (ts *TypescriptConverter) typeIDConstants(w *strings.Builder, indent string, inspecter *Inspecter) error {
+	ids, err := inspecter.TypeIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if !ts.emits(*id.Struct) {
+			continue
+		}
+
+		ts.constant(w, indent, TypeIDConstant(id.Struct), fmt.Sprint(id.ID))
+	}
+
+	return nil
+}
+
 func (ts *TypescriptConverter) Builder(w *strings.Builder, inspecter *Inspecter) error {
-	for _, imports := range inspecter.Comments.TypescriptImports {
-		w.WriteString(fmt.Sprintf("import %s;\n", imports))
+	if ts.Module && ts.Namespace != "" {
+		return errors.New("es modules cannot be combined with a namespace, drop one of them")
+	}
+
+	switch ts.Style {
+	case "", "interface", "type", "class":
+	default:
+		return fmt.Errorf("unknown declaration style %q, expected interface, type or class", ts.Style)
+	}
+
+	if ts.Style == "class" && ts.Guards {
+		return errors.New("guards check plain objects, revive classes with fromJSON instead")
+	}
+
+	err := ts.int64Types(inspecter)
+	if err != nil {
+		return err
+	}
+
+	err = ts.checkTime()
+	if err != nil {
+		return err
+	}
+
+	if ts.Zod {
+		if ts.Guards {
+			return errors.New("zod schemas check values with parse and safeParse, drop the guards")
+		}
+
+		if ts.Style == "class" {
+			return errors.New("zod schemas infer their types and cannot be combined with classes")
+		}
+
+		return ts.zodBuilder(w, inspecter)
+	}
+
+	if ts.Guards && ts.Declaration {
+		return errors.New("guards are functions and cannot be generated as a declaration file")
 	}
 
+	imports := ts.imports(inspecter)
+	for _, imp := range imports {
+		w.WriteString(fmt.Sprintf("import %s;\n", imp))
+	}
+
+	imported := ts.moduleImports(w, inspecter)
+
 	if ts.Namespace != "" {
+		if ts.Declaration {
+			w.WriteString("declare ")
+		}
 		w.WriteString(fmt.Sprintf("namespace %s {\n", ts.Namespace))
 	}
 
-	if len(inspecter.Comments.TypescriptImports) > 0 {
+	if len(imports) > 0 || imported {
 		w.WriteString("\n")
 	}
 
@@ -91,50 +317,82 @@ func (ts *TypescriptConverter) Builder(w *strings.Builder, inspecter *Inspecter)
 		interfaceMemberIndent = inspecter.Indent
 	}
 
+	ts.isoDateString(w, inspecter, interfaceIndent)
+	ts.scalars(w, inspecter, interfaceIndent)
+
 	for _, newStruct := range inspecter.Structs {
+		if !ts.emits(newStruct) {
+			continue
+		}
+
+		if ts.Constants {
+			ts.memberConstants(w, interfaceIndent, newStruct)
+		}
+
+		if ts.Style == "class" {
+			err := ts.class(w, inspecter, newStruct, interfaceIndent)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		w.WriteString(DocComment(interfaceIndent, newStruct.Comment, newStruct.Deprecated))
 		w.WriteString(interfaceIndent)
 
-		if ts.Namespace != "" {
-			w.WriteString("export interface ")
+		if ts.exported() {
+			w.WriteString("export ")
 		} else {
-			w.WriteString("declare interface ")
+			w.WriteString("declare ")
 		}
 
-		w.WriteString(newStruct.Name)
-		w.WriteString(" {\n")
+		if ts.Style == "type" {
+			w.WriteString(fmt.Sprintf("type %s = {\n", newStruct.Name))
+		} else {
+			w.WriteString(fmt.Sprintf("interface %s {\n", newStruct.Name))
+		}
 
 		for _, member := range newStruct.Members {
+			w.WriteString(DocComment(interfaceMemberIndent, member.Comment, member.Deprecated))
+			optional, nullable, err := ts.nullability(member)
+			if err != nil {
+				return err
+			}
+
 			w.WriteString(fmt.Sprintf("%s%s", interfaceMemberIndent, member.Name))
-			if member.Type.IsPointer {
+			if optional {
 				w.WriteString("?")
 			}
 
-			if member.Type.IsMap {
-				w.WriteString(fmt.Sprintf(": { [key: %s%s%s", member.Type.MapKey.Prefix, member.Type.MapKey.Value, member.Type.MapKey.Suffix))
-
-				w.WriteString(fmt.Sprintf("]: %s%s%s", member.Type.MapVal.Prefix, member.Type.MapVal.Value, member.Type.MapVal.Suffix))
+			w.WriteString(": " + ts.memberType(member))
 
-				w.WriteByte('}')
-				continue
-			} else {
-
-				w.WriteString(fmt.Sprintf(": %s%s%s", member.Type.Prefix, member.Type.Value, member.Type.Suffix))
-
-				if member.Type.IsArray {
-					w.WriteString("[]")
-				}
+			if nullable {
+				w.WriteString(" | null")
 			}
 
-			w.WriteByte(';')
+			w.WriteString(";\n")
+		}
+
+		if ts.Style == "type" {
+			w.WriteString(fmt.Sprintf("%s};\n\n", interfaceIndent))
+		} else {
+			w.WriteString(fmt.Sprintf("%s}\n\n", interfaceIndent))
+		}
 
-			if member.Comment != "" {
-				w.WriteString(fmt.Sprintf("%s// %s", inspecter.Indent, member.Comment))
+		if ts.Guards {
+			err := ts.guards(w, inspecter, newStruct, interfaceIndent)
+			if err != nil {
+				return err
 			}
-
-			w.WriteString("\n")
 		}
+	}
 
-		w.WriteString(fmt.Sprintf("%s}\n\n", interfaceIndent))
+	if ts.TypeIDs {
+		err := ts.typeIDConstants(w, interfaceIndent, inspecter)
+		if err != nil {
+			return err
+		}
 	}
 
 	if ts.Namespace != "" {
@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// basicGoType returns the go type a value is declared with, the basic type
+// of named scalar types
+func basicGoType(vt *ValueType) string {
+	if vt.Basic != "" {
+		return vt.Basic
+	}
+
+	return vt.GoType
+}
+
+// branded is whether a value is a named scalar type declared as a brand
+func (ts *TypescriptConverter) branded(vt *ValueType) bool {
+	return ts.Brands && vt.Scalar != nil
+}
+
+// brand wraps the expression creating the basic value of a branded value in
+// its constructor, leaving other values as they are
+func (ts *TypescriptConverter) brand(vt *ValueType, expr string) string {
+	if !ts.branded(vt) {
+		return expr
+	}
+
+	return tsCall(vt.Scalar.Name, expr)
+}
+
+// scalarBase returns the typescript type a named scalar type is declared
+// with, empty when it has none
+func (ts *TypescriptConverter) scalarBase(vt *ValueType) string {
+	switch vt.Kind {
+	case KindBool:
+		return "boolean"
+	case KindInt, KindUint:
+		if isInt64(basicGoType(vt)) {
+			return ts.int64Type(false)
+		}
+		return "number"
+	case KindFloat:
+		return "number"
+	case KindString:
+		return "string"
+	}
+
+	return ""
+}
+
+// scalarValueType classifies a named scalar type
+func (ts *TypescriptConverter) scalarValueType(inspecter *Inspecter, scalar Scalar) (*ValueType, error) {
+	return inspecter.ValueType(StructMemberType{GoValue: scalar.Package + "." + scalar.Name})
+}
+
+// scalarTypes maps the named scalar types to the typescript types they are declared with
+func (ts *TypescriptConverter) scalarTypes(inspecter *Inspecter) map[string]string {
+	types := make(map[string]string)
+	for _, scalar := range inspecter.Scalars {
+		vt, err := ts.scalarValueType(inspecter, scalar)
+		if err != nil {
+			continue
+		}
+
+		if base := ts.scalarBase(vt); base != "" {
+			types[scalar.Name] = base
+		}
+	}
+
+	return types
+}
+
+// scalars declares the named scalar types of the module as type aliases or,
+// when Brands is set, as branded types with a constructor function so a
+// UserID and an OrderID cannot be mixed up
+func (ts *TypescriptConverter) scalars(w *strings.Builder, inspecter *Inspecter, indent string) {
+	inner := indent + inspecter.Indent
+
+	for _, scalar := range inspecter.Scalars {
+		if !ts.emitsScalar(scalar) {
+			continue
+		}
+
+		vt, err := ts.scalarValueType(inspecter, scalar)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING! %s, leaving out the %s type\n", err, scalar.Name)
+			continue
+		}
+
+		base := ts.scalarBase(vt)
+		if base == "number" && isInt64(basicGoType(vt)) {
+			fmt.Fprintf(os.Stderr, "WARNING! the %s %s is emitted as number, values above 2^53 lose precision, use --int64 bigint\n", basicGoType(vt), scalar.Name)
+		}
+
+		w.WriteString(DocComment(indent, scalar.Comment, scalar.Deprecated))
+		w.WriteString(indent)
+		if ts.exported() {
+			w.WriteString("export ")
+		} else {
+			w.WriteString("declare ")
+		}
+
+		if !ts.Brands {
+			w.WriteString(fmt.Sprintf("type %s = %s;\n\n", scalar.Name, base))
+			continue
+		}
+
+		w.WriteString(fmt.Sprintf("type %s = %s & { readonly __brand: \"%s\" };\n\n", scalar.Name, base, scalar.Name))
+
+		w.WriteString(indent)
+		if ts.exported() {
+			w.WriteString("export ")
+		}
+		if ts.Declaration && ts.Namespace == "" {
+			w.WriteString("declare ")
+		}
+
+		if ts.Declaration {
+			w.WriteString(fmt.Sprintf("function %s(value: %s): %s;\n\n", scalar.Name, base, scalar.Name))
+			continue
+		}
+
+		w.WriteString(fmt.Sprintf("function %s(value: %s): %s {\n", scalar.Name, base, scalar.Name))
+		w.WriteString(fmt.Sprintf("%sreturn value as %s;\n", inner, scalar.Name))
+		w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+	}
+}
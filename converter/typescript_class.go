@@ -0,0 +1,481 @@
+package converter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// classNeedsConversion is whether a value differs between the class and json,
+// such as dates and nested classes
+func (ts *TypescriptConverter) classNeedsConversion(vt *ValueType) bool {
+	if ts.bigint(vt) || ts.userDuration(vt) {
+		return true
+	}
+
+	switch vt.Kind {
+	case KindTime:
+		return ts.timeType() == "Date" || ts.userTime()
+	case KindStruct:
+		return true
+	case KindPointer, KindSlice, KindMap:
+		return ts.classNeedsConversion(vt.Elem)
+	}
+
+	return false
+}
+
+// classType returns the typescript type of a class member
+func (ts *TypescriptConverter) classType(vt *ValueType) string {
+	if vt.Scalar != nil {
+		return vt.Scalar.Name
+	}
+
+	if ts.bigint(vt) {
+		return "bigint"
+	}
+
+	if vt.GoType == "time.Duration" {
+		return ts.durationType()
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return "boolean"
+	case KindInt, KindUint, KindFloat:
+		return "number"
+	case KindString:
+		return "string"
+	case KindTime:
+		return ts.timeType()
+	case KindStruct:
+		return vt.Struct.Name
+	case KindPointer:
+		return ts.classType(vt.Elem) + " | null"
+	case KindSlice:
+		elem := ts.classType(vt.Elem)
+		if strings.Contains(elem, "|") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case KindMap:
+		key := "string"
+		if vt.Key.Kind == KindInt || vt.Key.Kind == KindUint {
+			key = "number"
+		}
+		return fmt.Sprintf("{ [key: %s]: %s }", key, ts.classType(vt.Elem))
+	}
+
+	return "any"
+}
+
+// classZero returns the go zero value of a class member
+func (ts *TypescriptConverter) classZero(vt *ValueType) string {
+	if ts.bigint(vt) {
+		return ts.brand(vt, "0n")
+	}
+
+	if ts.userDuration(vt) {
+		return tsCall(ts.DurationParse, "0")
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return ts.brand(vt, "false")
+	case KindInt, KindUint, KindFloat:
+		return ts.brand(vt, "0")
+	case KindString:
+		if isInt64(vt.GoType) {
+			return `"0"`
+		}
+		return ts.brand(vt, `""`)
+	case KindTime:
+		switch ts.timeType() {
+		case "Date":
+			return "new Date(0)"
+		case "string":
+			return tsZeroTime
+		case tsISODateString:
+			return tsZeroTime + " as " + tsISODateString
+		}
+		return tsCall(ts.TimeParse, tsZeroTime)
+	case KindStruct:
+		return fmt.Sprintf("new %s()", vt.Struct.Name)
+	case KindSlice:
+		return "[]"
+	case KindMap:
+		return "{}"
+	}
+
+	return "null"
+}
+
+// classRevive returns the expression turning the json value expr into a class
+// member. Nested slices and maps can be null in json and become empty.
+func (ts *TypescriptConverter) classRevive(vt *ValueType, expr string, depth int) string {
+	elem := fmt.Sprintf("e%d", depth)
+
+	if ts.bigint(vt) {
+		return ts.brand(vt, fmt.Sprintf("BigInt(%s)", expr))
+	}
+
+	if ts.userDuration(vt) {
+		return tsCall(ts.DurationParse, expr)
+	}
+
+	switch vt.Kind {
+	case KindTime:
+		switch {
+		case ts.timeType() == "Date":
+			return fmt.Sprintf("new Date(%s)", expr)
+		case ts.userTime():
+			return tsCall(ts.TimeParse, expr)
+		}
+		return expr
+	case KindStruct:
+		return fmt.Sprintf("%s.fromJSON(%s)", vt.Struct.Name, expr)
+	case KindPointer:
+		if !ts.classNeedsConversion(vt.Elem) {
+			return expr
+		}
+		return fmt.Sprintf("%s === null ? null : %s", expr, ts.classRevive(vt.Elem, expr, depth))
+	case KindSlice:
+		if depth > 0 {
+			expr = fmt.Sprintf("(%s ?? [])", expr)
+		}
+		if !ts.classNeedsConversion(vt.Elem) {
+			return expr
+		}
+		return fmt.Sprintf("%s.map((%s: any) => %s)", expr, elem, ts.classRevive(vt.Elem, elem, depth+1))
+	case KindMap:
+		if depth > 0 {
+			expr = fmt.Sprintf("(%s ?? {})", expr)
+		}
+		if !ts.classNeedsConversion(vt.Elem) {
+			return expr
+		}
+		return fmt.Sprintf("Object.fromEntries(Object.entries(%s).map(([k%d, %s]: [string, any]) => [k%d, %s]))", expr, depth, elem, depth, ts.classRevive(vt.Elem, elem, depth+1))
+	}
+
+	return expr
+}
+
+// classDump returns the expression turning the class member expr into a json value
+func (ts *TypescriptConverter) classDump(vt *ValueType, expr string, depth int) string {
+	elem := fmt.Sprintf("e%d", depth)
+
+	if ts.bigint(vt) {
+		// JSON.stringify cannot write bigints, classMembers only lets through
+		// the ,string members toJSON writes as strings
+		return fmt.Sprintf("String(%s)", expr)
+	}
+
+	if ts.userDuration(vt) {
+		return tsCall(ts.DurationFormat, expr)
+	}
+
+	switch vt.Kind {
+	case KindTime:
+		switch {
+		case ts.timeType() == "Date":
+			return fmt.Sprintf("%s.toISOString()", expr)
+		case ts.userTime() && ts.TimeFormat != "":
+			return tsCall(ts.TimeFormat, expr)
+		}
+		// JSON.stringify calls the toJSON method of user types
+		return expr
+	case KindStruct:
+		return fmt.Sprintf("%s.toJSON()", expr)
+	case KindPointer:
+		dump := ts.classDump(vt.Elem, expr, depth)
+		if dump == expr {
+			return expr
+		}
+		return fmt.Sprintf("%s === null ? null : %s", expr, dump)
+	case KindSlice:
+		// user times are revived but left to JSON.stringify
+		dump := ts.classDump(vt.Elem, elem, depth+1)
+		if dump == elem {
+			return expr
+		}
+		return fmt.Sprintf("%s.map((%s) => %s)", expr, elem, dump)
+	case KindMap:
+		dump := ts.classDump(vt.Elem, elem, depth+1)
+		if dump == elem {
+			return expr
+		}
+		return fmt.Sprintf("Object.fromEntries(Object.entries(%s).map(([k%d, %s]) => [k%d, %s]))", expr, depth, elem, depth, dump)
+	}
+
+	return expr
+}
+
+// classNotEmpty returns the check for values omitempty keeps, empty when every value is kept
+func (ts *TypescriptConverter) classNotEmpty(vt *ValueType, expr string) string {
+	if ts.bigint(vt) {
+		return expr + " !== 0n"
+	}
+
+	if ts.userDuration(vt) {
+		return fmt.Sprintf("%s !== 0", tsCall(ts.DurationFormat, expr))
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return expr
+	case KindInt, KindUint, KindFloat:
+		return expr + " !== 0"
+	case KindString:
+		if isInt64(vt.GoType) {
+			return expr + ` !== "0"`
+		}
+		return expr + ` !== ""`
+	case KindSlice:
+		return expr + ".length > 0"
+	case KindMap:
+		return fmt.Sprintf("Object.keys(%s).length > 0", expr)
+	}
+
+	return ""
+}
+
+// classBigintNumber is whether toJSON writes a bigint of a value as a json
+// number, only the ,string option of a plain member makes it a string
+func (ts *TypescriptConverter) classBigintNumber(vt *ValueType, jsonString bool) bool {
+	if ts.bigint(vt) {
+		return !jsonString
+	}
+
+	switch vt.Kind {
+	case KindPointer:
+		return ts.classBigintNumber(vt.Elem, jsonString)
+	case KindSlice, KindMap:
+		return ts.classBigintNumber(vt.Elem, false)
+	}
+
+	return false
+}
+
+// classMember is a member of a class, with the value of pointers unwrapped
+// because their nullability depends on the nullability model
+type classMember struct {
+	StructMember
+	Value    *ValueType // nil for members copied as they are
+	Optional bool
+	Nullable bool
+	TSType   string
+}
+
+func (ts *TypescriptConverter) classMembers(inspecter *Inspecter, s Struct) ([]classMember, error) {
+	var members []classMember
+
+	for _, member := range s.Members {
+		optional, nullable, err := ts.nullability(member)
+		if err != nil {
+			return nil, err
+		}
+
+		m := classMember{StructMember: member, Optional: optional, Nullable: nullable}
+
+		declared := false
+		if member.Tags != nil {
+			_, err := member.Tags.Get("tstype")
+			declared = err == nil
+		}
+
+		if declared {
+			// the tstype tag is kept as it is
+		} else if len(member.OneOf) > 0 {
+			// a nil interface{} is null
+			m.Nullable = true
+		} else if vt, err := ts.memberValueType(inspecter, member); err == nil {
+			if vt.Kind == KindPointer {
+				vt = vt.Elem
+			}
+			m.Value = ts.int64Value(member, vt)
+
+			// toJSON can only write plain ,string members as strings, other
+			// bigints would become json numbers and lose their precision
+			plain := member.JSONString && !member.Type.IsArray && !member.Type.IsMap
+			if !member.JSONIgnore && ts.classBigintNumber(m.Value, plain) {
+				return nil, fmt.Errorf("%s.%s: toJSON would write the bigint as a json number and lose precision above 2^53, add the ,string json option to a plain member or use --int64 string", s.Name, member.Name)
+			}
+		}
+
+		if m.Value != nil {
+			m.TSType = ts.classType(m.Value)
+		} else {
+			m.TSType = ts.memberType(member)
+		}
+
+		if m.Nullable {
+			m.TSType += " | null"
+		}
+
+		members = append(members, m)
+	}
+
+	return members, nil
+}
+
+// class writes a class with a constructor, fromJSON and toJSON instead of an
+// interface, reviving dates and nested classes
+func (ts *TypescriptConverter) class(w *strings.Builder, inspecter *Inspecter, s Struct, indent string) error {
+	members, err := ts.classMembers(inspecter, s)
+	if err != nil {
+		return err
+	}
+
+	inner := indent + inspecter.Indent
+	body := inner + inspecter.Indent
+
+	w.WriteString(DocComment(indent, s.Comment, s.Deprecated))
+	w.WriteString(indent)
+	if ts.exported() {
+		w.WriteString("export ")
+	}
+	if ts.Declaration && ts.Namespace == "" {
+		w.WriteString("declare ")
+	}
+	w.WriteString(fmt.Sprintf("class %s {\n", s.Name))
+
+	for _, m := range members {
+		w.WriteString(DocComment(inner, m.Comment, m.Deprecated))
+
+		switch {
+		case m.Optional:
+			w.WriteString(fmt.Sprintf("%s%s?: %s;\n", inner, m.Name, m.TSType))
+		case ts.Declaration:
+			w.WriteString(fmt.Sprintf("%s%s: %s;\n", inner, m.Name, m.TSType))
+		case m.Nullable:
+			w.WriteString(fmt.Sprintf("%s%s: %s = null;\n", inner, m.Name, m.TSType))
+		case m.Value != nil:
+			w.WriteString(fmt.Sprintf("%s%s: %s = %s;\n", inner, m.Name, m.TSType, ts.classZero(m.Value)))
+		default:
+			w.WriteString(fmt.Sprintf("%s%s!: %s;\n", inner, m.Name, m.TSType))
+		}
+	}
+
+	if len(members) > 0 {
+		w.WriteString("\n")
+	}
+
+	if ts.Declaration {
+		w.WriteString(fmt.Sprintf("%sconstructor(init?: Partial<%s>);\n", inner, s.Name))
+		w.WriteString(fmt.Sprintf("%sstatic fromJSON(o: any): %s;\n", inner, s.Name))
+		w.WriteString(fmt.Sprintf("%stoJSON(): object;\n", inner))
+		w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+		return nil
+	}
+
+	w.WriteString(fmt.Sprintf("%sconstructor(init?: Partial<%s>) {\n", inner, s.Name))
+	w.WriteString(fmt.Sprintf("%sObject.assign(this, init);\n", body))
+	w.WriteString(fmt.Sprintf("%s}\n\n", inner))
+
+	// fromJSON follows encoding/json: missing members and nulls leave the zero value
+	w.WriteString(fmt.Sprintf("%sstatic fromJSON(o: any): %s {\n", inner, s.Name))
+	w.WriteString(fmt.Sprintf("%sif (typeof o !== \"object\" || o === null) {\n", body))
+	w.WriteString(fmt.Sprintf("%s%sthrow new TypeError(\"%s.fromJSON: expected an object\");\n", body, inspecter.Indent, s.Name))
+	w.WriteString(fmt.Sprintf("%s}\n\n", body))
+	w.WriteString(fmt.Sprintf("%sconst x = new %s();\n", body, s.Name))
+
+	read := 0
+	for _, m := range members {
+		if m.JSONIgnore {
+			continue
+		}
+
+		if read == 0 {
+			w.WriteString(fmt.Sprintf("%slet v: any;\n", body))
+		}
+		read++
+
+		var value string
+		switch {
+		case len(m.OneOf) > 0:
+			value = "null"
+			for i := len(m.OneOf) - 1; i >= 0; i-- {
+				variant := m.OneOf[i]
+				value = fmt.Sprintf("v.kind === \"%s\" ? Object.assign(%s.fromJSON(v), { kind: \"%s\" as const }) : %s", variant.Name, variant.Type, variant.Name, value)
+			}
+		case m.Value == nil:
+			value = "v"
+		case m.JSONString && !ts.classNeedsConversion(m.Value) && (m.Value.Kind == KindInt || m.Value.Kind == KindUint || m.Value.Kind == KindFloat):
+			value = ts.brand(m.Value, "Number(v)")
+		default:
+			value = ts.classRevive(m.Value, "v", 0)
+		}
+
+		w.WriteString(fmt.Sprintf("\n%sv = o[%s];\n", body, strconv.Quote(m.JSONName)))
+		w.WriteString(fmt.Sprintf("%sif (v !== undefined && v !== null) {\n", body))
+		w.WriteString(fmt.Sprintf("%s%sx.%s = %s;\n", body, inspecter.Indent, m.Name, value))
+		if m.Nullable {
+			w.WriteString(fmt.Sprintf("%s} else if (v === null) {\n", body))
+			w.WriteString(fmt.Sprintf("%s%sx.%s = null;\n", body, inspecter.Indent, m.Name))
+		}
+		w.WriteString(fmt.Sprintf("%s}\n", body))
+	}
+
+	w.WriteString(fmt.Sprintf("\n%sreturn x;\n", body))
+	w.WriteString(fmt.Sprintf("%s}\n\n", inner))
+
+	w.WriteString(fmt.Sprintf("%stoJSON(): object {\n", inner))
+	w.WriteString(fmt.Sprintf("%sconst o: { [key: string]: unknown } = {};\n", body))
+
+	for _, m := range members {
+		if m.JSONIgnore {
+			continue
+		}
+
+		expr := "this." + m.Name
+
+		var conditions []string
+		if m.JSONOmitEmpty {
+			if m.Optional || m.Nullable {
+				conditions = append(conditions, expr+" != null")
+			}
+			if m.Value != nil && !m.Type.IsPointer {
+				// omitempty drops nil pointers but keeps pointers to empty values
+				if notEmpty := ts.classNotEmpty(m.Value, expr); notEmpty != "" {
+					conditions = append(conditions, notEmpty)
+				}
+			}
+		} else if m.Optional {
+			conditions = append(conditions, expr+" !== undefined")
+		}
+
+		// null only gets past the conditions when omitempty does not drop it
+		mayBeNull := m.Nullable && !m.JSONOmitEmpty
+
+		var value string
+		switch {
+		case len(m.OneOf) > 0:
+			value = fmt.Sprintf("{ ...%s.toJSON(), kind: %s.kind }", expr, expr)
+		case m.Value == nil:
+			value = expr
+		case m.JSONString && (m.Value.Kind == KindInt || m.Value.Kind == KindUint || m.Value.Kind == KindFloat):
+			value = fmt.Sprintf("String(%s)", expr)
+		default:
+			value = ts.classDump(m.Value, expr, 0)
+		}
+
+		if mayBeNull && value != expr {
+			value = fmt.Sprintf("%s === null ? null : %s", expr, value)
+		}
+
+		if len(conditions) == 0 {
+			w.WriteString(fmt.Sprintf("%so[%s] = %s;\n", body, strconv.Quote(m.JSONName), value))
+			continue
+		}
+
+		w.WriteString(fmt.Sprintf("%sif (%s) {\n", body, strings.Join(conditions, " && ")))
+		w.WriteString(fmt.Sprintf("%s%so[%s] = %s;\n", body, inspecter.Indent, strconv.Quote(m.JSONName), value))
+		w.WriteString(fmt.Sprintf("%s}\n", body))
+	}
+
+	w.WriteString(fmt.Sprintf("%sreturn o;\n", body))
+	w.WriteString(fmt.Sprintf("%s}\n", inner))
+	w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassBigintsNeedJSONStrings(t *testing.T) {
+	for _, tc := range []struct {
+		member string
+		err    bool
+	}{
+		{"ID int64 `json:\"id,string\"`", false},
+		{"ID *uint64 `json:\"id,string\"`", false},
+		{"ID int64 `json:\"id\"`", true},
+		{"IDs []int64 `json:\"ids,string\"`", true},
+		{"IDs map[string]uint64 `json:\"ids\"`", true},
+		{"Secret int64 `json:\"-\"`", false},
+	} {
+		input := writeInput(t, "package example\n\ntype Row struct {\n\t"+tc.member+"\n}\n")
+
+		inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Style: "class", Int64: "bigint"}, Indent: "\t"}
+		files, err := inspecter.ConvertToFiles([]string{input}, "Row")
+		if tc.err {
+			if err == nil || !strings.Contains(err.Error(), "lose precision") {
+				t.Errorf("%s: expected a precision error, got %v", tc.member, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.member, err)
+			continue
+		}
+		if strings.Contains(files[0].Content, "Number(") {
+			t.Errorf("%s: toJSON writes a number:\n%s", tc.member, files[0].Content)
+		}
+	}
+}
@@ -0,0 +1,528 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tsFactoriesModule is the module the factories of split modules are written to
+const tsFactoriesModule = "factories"
+
+// tsFactoryTime is the time the factories fill time.Time members with
+const tsFactoryTime = `"2000-01-01T00:00:00Z"`
+
+// tsDeepPartial lets the overrides of a factory leave out nested members,
+// arrays and scalars are replaced as a whole
+const tsDeepPartial = `export type DeepPartial<T> = T extends string | number | bigint | boolean | Date | readonly unknown[]
+	? T
+	: T extends object
+	? { [K in keyof T]?: DeepPartial<T[K]> }
+	: T;
+`
+
+// tsMerge merges the overrides into the defaults a factory created, plain
+// objects are merged into nested structs and maps, everything else replaces the default
+const tsMerge = `function merge<T>(target: T, overrides?: DeepPartial<T>): T {
+	const t = target as { [key: string]: unknown };
+	for (const [key, value] of Object.entries(overrides ?? {})) {
+		const current = t[key];
+		const plain = typeof value === "object" && value !== null && Object.getPrototypeOf(value) === Object.prototype;
+		if (plain && typeof current === "object" && current !== null && !Array.isArray(current) && !(current instanceof Date)) {
+			merge(current, value as DeepPartial<object>);
+		} else if (value !== undefined) {
+			t[key] = value;
+		}
+	}
+	return target;
+}
+`
+
+// factoryImports collects the names the factories module imports from the
+// modules declaring them
+type factoryImports struct {
+	single  string   // the module of a single file, empty when split
+	modules []string // in the order they are first imported from
+	types   map[string][]string
+	values  map[string][]string
+	seen    map[string]bool
+}
+
+func (imports *factoryImports) add(value bool, name string, module string) {
+	if imports.seen[name] {
+		return
+	}
+	imports.seen[name] = true
+
+	if imports.single != "" {
+		module = imports.single
+	}
+
+	if _, ok := imports.types[module]; !ok {
+		imports.modules = append(imports.modules, module)
+		imports.types[module] = nil
+	}
+
+	if value {
+		imports.values[module] = append(imports.values[module], name)
+	} else {
+		imports.types[module] = append(imports.types[module], name)
+	}
+}
+
+// write writes the import type and import lines of every module
+func (imports *factoryImports) write(w *strings.Builder) {
+	for _, module := range imports.modules {
+		if names := imports.types[module]; len(names) > 0 {
+			sort.Strings(names)
+			w.WriteString(fmt.Sprintf("import type { %s } from \"./%s\";\n", strings.Join(names, ", "), module))
+		}
+		if names := imports.values[module]; len(names) > 0 {
+			sort.Strings(names)
+			w.WriteString(fmt.Sprintf("import { %s } from \"./%s\";\n", strings.Join(names, ", "), module))
+		}
+	}
+}
+
+// factoryNumber returns a number satisfying the required, oneof and numeric
+// bound rules of a member, 0 when it has none
+func factoryNumber(integer bool, unsigned bool, rules []ValidateRule) string {
+	value := 0.0
+	lo := math.Inf(-1)
+	hi := math.Inf(1)
+	if unsigned {
+		lo = 0
+	}
+
+	for _, rule := range rules {
+		param, err := strconv.ParseFloat(rule.Param, 64)
+		switch rule.Name {
+		case "oneof":
+			if values := strings.Fields(rule.Param); len(values) > 0 {
+				return values[0]
+			}
+		case "required":
+			value = 1
+		case "min", "gte":
+			if err == nil {
+				lo = math.Max(lo, param)
+			}
+		case "gt":
+			if err == nil {
+				lo = math.Max(lo, param+1)
+			}
+		case "max", "lte":
+			if err == nil {
+				hi = math.Min(hi, param)
+			}
+		case "lt":
+			if err == nil {
+				hi = math.Min(hi, param-1)
+			}
+		}
+	}
+
+	if integer {
+		lo = math.Ceil(lo)
+		hi = math.Floor(hi)
+	}
+
+	if value < lo {
+		value = lo
+	}
+	if value > hi {
+		value = hi
+	}
+
+	if integer {
+		return strconv.FormatInt(int64(value), 10)
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// factoryString returns a string satisfying the oneof, format and length
+// rules of a member, its json name when it has none
+func factoryString(name string, rules []ValidateRule) string {
+	value := name
+	if value == "" {
+		value = "value"
+	}
+
+	word := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, value)
+	if word == "" {
+		word = "value"
+	}
+
+	for _, rule := range rules {
+		switch rule.Name {
+		case "oneof":
+			if values := strings.Fields(rule.Param); len(values) > 0 {
+				return strings.Trim(values[0], "'")
+			}
+		case "email":
+			value = word + "@example.com"
+		case "url", "uri", "http_url":
+			value = "https://example.com/" + word
+		case "uuid", "uuid4":
+			value = "00000000-0000-4000-8000-000000000000"
+		case "ip", "ipv4":
+			value = "192.0.2.1"
+		case "ipv6":
+			value = "2001:db8::1"
+		case "alpha":
+			value = strings.Map(func(r rune) rune {
+				if r < unicode.MaxASCII && unicode.IsLetter(r) {
+					return r
+				}
+				return -1
+			}, value)
+			if value == "" {
+				value = "value"
+			}
+		case "alphanum":
+			value = word
+		case "numeric":
+			value = "0"
+		}
+	}
+
+	for _, rule := range rules {
+		switch rule.Name {
+		case "startswith":
+			if !strings.HasPrefix(value, rule.Param) {
+				value = rule.Param + value
+			}
+		case "endswith":
+			if !strings.HasSuffix(value, rule.Param) {
+				value += rule.Param
+			}
+		case "contains":
+			if !strings.Contains(value, rule.Param) {
+				value += rule.Param
+			}
+		}
+	}
+
+	length := func(names ...string) (int, bool) {
+		for _, rule := range rules {
+			for _, name := range names {
+				if rule.Name != name {
+					continue
+				}
+				if n, err := strconv.Atoi(rule.Param); err == nil && n >= 0 {
+					return n, true
+				}
+			}
+		}
+		return 0, false
+	}
+
+	min, hasMin := length("min", "gte")
+	max, hasMax := length("max", "lte")
+	if n, ok := length("len"); ok {
+		min, max, hasMin, hasMax = n, n, true, true
+	}
+
+	if hasMin && len(value) < min {
+		value += strings.Repeat("x", min-len(value))
+	}
+	if hasMax && len(value) > max {
+		value = value[:max]
+	}
+
+	return value
+}
+
+// factoryCount returns the number of elements the length rules of a slice or map ask for
+func factoryCount(rules []ValidateRule) int {
+	count := 0
+	for _, rule := range rules {
+		n, err := strconv.Atoi(rule.Param)
+		if err != nil || n < 0 {
+			continue
+		}
+
+		switch rule.Name {
+		case "len":
+			return n
+		case "min", "gte":
+			if n > count {
+				count = n
+			}
+		case "gt":
+			if n+1 > count {
+				count = n + 1
+			}
+		}
+	}
+
+	return count
+}
+
+// factoryValue returns the default of a value. The rules only apply to the
+// member itself, not to the elements of its slices and maps.
+func (ts *TypescriptConverter) factoryValue(vt *ValueType, name string, rules []ValidateRule, imports *factoryImports) string {
+	if vt.Scalar != nil && ts.Brands {
+		imports.add(true, vt.Scalar.Name, ts.moduleOf(vt.Scalar.Package, vt.Scalar.Source))
+	}
+
+	if value, ok := ts.factoryConst(vt, rules); ok {
+		return value
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		for _, rule := range rules {
+			if rule.Name == "required" {
+				// a required bool must be true
+				return ts.brand(vt, "true")
+			}
+		}
+		return ts.brand(vt, "false")
+	case KindInt, KindUint, KindFloat:
+		n := factoryNumber(vt.Kind != KindFloat, vt.Kind == KindUint, rules)
+		switch {
+		case ts.bigint(vt):
+			n += "n"
+		case ts.userDuration(vt):
+			return tsCall(ts.DurationParse, n)
+		}
+		return ts.brand(vt, n)
+	case KindString:
+		if isInt64(vt.GoType) {
+			// a 64 bit integer kept as the string encoding/json writes
+			return strconv.Quote(factoryNumber(true, vt.GoType == "uint64", rules))
+		}
+		if vt.GoType == "[]byte" {
+			// a base64 string
+			return `""`
+		}
+		return ts.brand(vt, strconv.Quote(factoryString(name, rules)))
+	case KindTime:
+		switch ts.timeType() {
+		case "string":
+			return tsFactoryTime
+		case "Date":
+			return fmt.Sprintf("new Date(%s)", tsFactoryTime)
+		case tsISODateString:
+			imports.add(false, tsISODateString, tsScalarsModule)
+			return tsFactoryTime + " as " + tsISODateString
+		}
+		return tsCall(ts.TimeParse, tsFactoryTime)
+	case KindStruct:
+		return factoryName(vt.Struct.Name) + "()"
+	case KindPointer:
+		return ts.factoryValue(vt.Elem, name, nil, imports)
+	case KindSlice:
+		var elems []string
+		for i := 0; i < factoryCount(rules); i++ {
+			elems = append(elems, ts.factoryValue(vt.Elem, name, nil, imports))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case KindMap:
+		count := factoryCount(rules)
+		if count == 0 {
+			return "{}"
+		}
+
+		var entries []string
+		for i := 1; i <= count; i++ {
+			key := fmt.Sprintf("%q", fmt.Sprintf("key%d", i))
+			if vt.Key.Kind == KindInt || vt.Key.Kind == KindUint {
+				key = strconv.Itoa(i)
+			}
+			entries = append(entries, fmt.Sprintf("%s: %s", key, ts.factoryValue(vt.Elem, name, nil, imports)))
+		}
+		return "{ " + strings.Join(entries, ", ") + " }"
+	}
+
+	return "null"
+}
+
+// factoryConst returns the first const declared with the named scalar type
+// of a value, the zero value is rarely one of the values of an enum. A oneof
+// rule of the member wins over the consts.
+func (ts *TypescriptConverter) factoryConst(vt *ValueType, rules []ValidateRule) (string, bool) {
+	if vt.Scalar == nil || len(vt.Scalar.Consts) == 0 {
+		return "", false
+	}
+
+	for _, rule := range rules {
+		if rule.Name == "oneof" {
+			return "", false
+		}
+	}
+
+	value := constLiteral(vt.Scalar.Consts[0].Value)
+	switch {
+	case ts.bigint(vt):
+		value += "n"
+	case ts.userDuration(vt):
+		return tsCall(ts.DurationParse, value), true
+	}
+
+	return ts.brand(vt, value), true
+}
+
+// factoryName returns the name of the factory of a struct, makeOrder for order
+func factoryName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return "make" + string(unicode.ToUpper(r)) + name[size:]
+}
+
+// factory writes the makeX function of a struct, filling its members with
+// the defaults the overrides are merged into
+func (ts *TypescriptConverter) factory(w *strings.Builder, inspecter *Inspecter, s Struct, imports *factoryImports) error {
+	indent := inspecter.Indent
+
+	imports.add(ts.Style == "class", s.Name, ts.moduleOf(s.Package, s.Source))
+
+	var entries []string
+	for _, member := range s.Members {
+		// the defaults are keyed like the properties of the emitted types, only
+		// zod schemas infer json names and leave out json:"-" members
+		key := member.Name
+		if ts.Zod {
+			if member.JSONIgnore {
+				continue
+			}
+			key = zodKey(member.JSONName)
+		}
+
+		optional, nullable, err := ts.nullability(member)
+		if err != nil {
+			return err
+		}
+
+		rules := member.Validate
+		for _, rule := range rules {
+			if rule.Name == "-" {
+				rules = nil
+				break
+			}
+		}
+
+		// nil pointers are left empty unless the rules require them, which also
+		// keeps recursive structs from creating each other forever
+		_, required := member.ValidateRule("required")
+		if member.Type.IsPointer && !required {
+			switch {
+			case nullable:
+				entries = append(entries, fmt.Sprintf("%s: null", key))
+				continue
+			case optional:
+				continue
+			}
+		}
+
+		var value string
+		if len(member.OneOf) > 0 {
+			variant := member.OneOf[0]
+			value = fmt.Sprintf("Object.assign(%s(), { kind: \"%s\" as const })", factoryName(variant.Type), variant.Name)
+		} else if vt, err := ts.memberValueType(inspecter, member); err == nil && (ts.Zod || !hasTag(member, "tstype")) {
+			if vt.Kind == KindPointer {
+				vt = vt.Elem
+			}
+			value = ts.factoryValue(ts.int64Value(member, vt), member.JSONName, rules, imports)
+		} else if ts.Zod || member.GoType.GoValue == "interface{}" || member.GoType.GoValue == "any" {
+			value = "null"
+		} else {
+			switch ts.memberType(member) {
+			case "any", "unknown":
+				value = "null"
+			case "string":
+				value = strconv.Quote(factoryString(member.JSONName, rules))
+			case "number":
+				value = factoryNumber(false, false, rules)
+			case "boolean":
+				value = "false"
+			default:
+				return fmt.Errorf("%s.%s: %s has no factory default, declare it with a go type or a tstype of string, number or boolean", s.Name, member.Name, ts.memberType(member))
+			}
+		}
+
+		entries = append(entries, fmt.Sprintf("%s: %s", key, value))
+	}
+
+	defaults := "{}"
+	if len(entries) > 0 {
+		defaults = fmt.Sprintf("{\n%s%s%s,\n%s}", indent, indent, strings.Join(entries, ",\n"+indent+indent), indent)
+	}
+	if ts.Style == "class" {
+		defaults = fmt.Sprintf("Object.assign(new %s(), %s)", s.Name, defaults)
+	}
+
+	w.WriteString(fmt.Sprintf("export function %s(overrides?: DeepPartial<%s>): %s {\n", factoryName(s.Name), s.Name, s.Name))
+	w.WriteString(fmt.Sprintf("%sreturn merge<%s>(%s, overrides);\n", indent, s.Name, defaults))
+	w.WriteString("}\n\n")
+
+	return nil
+}
+
+// hasTag is whether a member has the named struct tag
+func hasTag(member StructMember, name string) bool {
+	if member.Tags == nil {
+		return false
+	}
+
+	_, err := member.Tags.Get(name)
+	return err == nil
+}
+
+// factories generates the module of makeX test data factories, importing the
+// types from the modules generated next to it
+func (ts *TypescriptConverter) factories(inspecter *Inspecter, name string) (string, error) {
+	if ts.Declaration {
+		return "", errors.New("factories are functions and cannot be generated next to a declaration file")
+	}
+
+	if ts.Namespace != "" || (ts.Split == "" && !ts.exported()) {
+		return "", errors.New("factories import the types from an es module, add --module")
+	}
+
+	if ts.Zod && ts.Nullability == "" {
+		// the types the schemas infer follow encoding/json
+		model := *ts
+		model.Nullability = "json"
+		ts = &model
+	}
+
+	imports := &factoryImports{types: make(map[string][]string), values: make(map[string][]string), seen: make(map[string]bool)}
+	if ts.Split == "" {
+		imports.single = name
+	}
+
+	body := new(strings.Builder)
+	body.WriteString(tsDeepPartial)
+	body.WriteString("\n")
+	body.WriteString(tsMerge)
+	body.WriteString("\n")
+
+	for _, s := range inspecter.Structs {
+		err := ts.factory(body, inspecter, s, imports)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	w := new(strings.Builder)
+	for _, imports := range inspecter.Comments.TypescriptImports {
+		w.WriteString(fmt.Sprintf("import %s;\n", imports))
+	}
+	imports.write(w)
+	w.WriteString("\n")
+	w.WriteString(body.String())
+
+	return strings.TrimSuffix(w.String(), "\n"), nil
+}
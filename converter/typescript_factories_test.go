@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFactoriesDefaultToTheFirstEnumConst(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Kind string
+
+const (
+	KindA Kind = "a"
+	KindB Kind = "b"
+)
+
+type Level int
+
+const (
+	LevelLow Level = iota + 3
+	LevelHigh
+)
+
+type Task struct {
+	Kind  Kind
+	Level Level
+	Other Kind `+"`validate:\"oneof=b\"`"+`
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Factories: true}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "Task")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("expected the types and the factories, got %d files", len(files))
+	}
+
+	content := files[1].Content
+	for _, value := range []string{`Kind: "a"`, `Level: 3`, `Other: "b"`} {
+		if !strings.Contains(content, value) {
+			t.Errorf("makeTask does not default to %s:\n%s", value, content)
+		}
+	}
+}
+
+func TestFactoriesRejectTSTypeMembers(t *testing.T) {
+	input := writeInput(t, `package example
+
+import "time"
+
+type Event struct {
+	At time.Time `+"`tstype:\"moment.Moment\"`"+`
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Factories: true}, Indent: "\t"}
+	_, err := inspecter.ConvertToFiles([]string{input}, "Event")
+	if err == nil || !strings.Contains(err.Error(), "Event.At") {
+		t.Fatalf("expected an error naming Event.At, got %v", err)
+	}
+}
+
+func TestFactoriesUsePropertyNames(t *testing.T) {
+	input := writeInput(t, `package example
+
+type User struct {
+	ID       int    `+"`json:\"id\"`"+`
+	FullName string `+"`json:\"full-name\"`"+`
+	Secret   string `+"`json:\"-\"`"+`
+	Plain    bool
+}
+`)
+
+	for _, tc := range []struct {
+		name    string
+		ts      *TypescriptConverter
+		keys    []string
+		missing []string
+	}{
+		{"interface", &TypescriptConverter{Module: true, Factories: true}, []string{"ID: 0", `FullName: "full-name"`, `Secret: "Secret"`, "Plain: false"}, []string{"id:", `"full-name":`}},
+		{"class", &TypescriptConverter{Module: true, Factories: true, Style: "class"}, []string{"ID: 0", `FullName: "full-name"`, `Secret: "Secret"`}, []string{"id:", `"full-name":`}},
+		{"zod", &TypescriptConverter{Module: true, Factories: true, Zod: true}, []string{"id: 0", `"full-name": "full-name"`, "Plain: false"}, []string{"ID:", "FullName:", "Secret:"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inspecter := &Inspecter{Converter: tc.ts, Indent: "\t"}
+			files, err := inspecter.ConvertToFiles([]string{input}, "User")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content := files[1].Content
+			for _, key := range tc.keys {
+				if !strings.Contains(content, key) {
+					t.Errorf("makeUser does not default %s:\n%s", key, content)
+				}
+			}
+
+			for _, key := range tc.missing {
+				if strings.Contains(content, key) {
+					t.Errorf("makeUser defaults %s, which the type does not declare:\n%s", key, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// tsCheck returns the expression checking that expr holds a value of the
+// member type, false when the type cannot be checked at runtime. Named scalar
+// types are checked as the types they are declared with.
+func tsCheck(t StructMemberType, expr string, depth int, structs map[string]bool, scalars map[string]string) (string, bool) {
+	elem := fmt.Sprintf("e%d", depth)
+
+	if t.IsMap {
+		if t.MapVal == nil {
+			return "", false
+		}
+
+		// json object keys are always strings, only the values are checked
+		check, ok := tsCheck(*t.MapVal, elem, depth+1, structs, scalars)
+		if !ok {
+			return "", false
+		}
+
+		return fmt.Sprintf("typeof %s === \"object\" && %s !== null && !Array.isArray(%s) && Object.values(%s).every((%s) => %s)", expr, expr, expr, expr, elem, check), true
+	}
+
+	if t.IsArray {
+		t.IsArray = false
+		check, ok := tsCheck(t, elem, depth+1, structs, scalars)
+		if !ok {
+			return "", false
+		}
+
+		return fmt.Sprintf("Array.isArray(%s) && %s.every((%s) => %s)", expr, expr, elem, check), true
+	}
+
+	if t.Prefix != "" || t.Suffix != "" {
+		return "", false
+	}
+
+	switch t.Value {
+	case "number", "string", "boolean", "bigint":
+		return fmt.Sprintf("typeof %s === \"%s\"", expr, t.Value), true
+	case tsISODateString:
+		return fmt.Sprintf("typeof %s === \"string\"", expr), true
+	case "Date":
+		return fmt.Sprintf("%s instanceof Date", expr), true
+	case "any", "unknown":
+		return "true", true
+	}
+
+	if structs[t.Value] {
+		return fmt.Sprintf("is%s(%s)", t.Value, expr), true
+	}
+
+	if base, ok := scalars[t.Value]; ok {
+		t.Value = base
+		return tsCheck(t, expr, depth, structs, scalars)
+	}
+
+	return "", false
+}
+
+// tsJSONStringCheck returns the expression accepting the json string
+// encoding/json quotes the numbers and bools of ,string members in
+func tsJSONStringCheck(member StructMember, expr string, scalars map[string]string) (string, bool) {
+	t := member.Type
+	if !member.JSONString || t.IsArray || t.IsMap || t.Prefix != "" || t.Suffix != "" {
+		return "", false
+	}
+
+	value := t.Value
+	if base, ok := scalars[value]; ok {
+		value = base
+	}
+
+	switch value {
+	case "number", "bigint":
+		return fmt.Sprintf("(typeof %s === \"string\" && /^-?[0-9]+(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$/.test(%s))", expr, expr), true
+	case "boolean":
+		return fmt.Sprintf("%s === \"true\" || %s === \"false\"", expr, expr), true
+	}
+
+	return "", false
+}
+
+// guards writes the isX and assertX functions of a struct, checking the
+// members the way its interface declares them
+func (ts *TypescriptConverter) guards(w *strings.Builder, inspecter *Inspecter, s Struct, indent string) error {
+	structs := make(map[string]bool)
+	for _, other := range inspecter.Structs {
+		structs[other.Name] = true
+	}
+	scalars := ts.scalarTypes(inspecter)
+
+	inner := indent + inspecter.Indent
+	export := ""
+	if ts.exported() {
+		export = "export "
+	}
+
+	w.WriteString(fmt.Sprintf("%s%sfunction is%s(value: unknown): value is %s {\n", indent, export, s.Name, s.Name))
+	w.WriteString(fmt.Sprintf("%sif (typeof value !== \"object\" || value === null || Array.isArray(value)) {\n", inner))
+	w.WriteString(fmt.Sprintf("%s%sreturn false;\n", inner, inspecter.Indent))
+	w.WriteString(fmt.Sprintf("%s}\n", inner))
+
+	type memberCheck struct {
+		name  string
+		check string
+	}
+
+	var checks []memberCheck
+	for _, member := range s.Members {
+		optional, nullable, err := ts.nullability(member)
+		if err != nil {
+			return err
+		}
+
+		var check string
+		if len(member.OneOf) > 0 {
+			var variants []string
+			for _, variant := range member.OneOf {
+				variants = append(variants, fmt.Sprintf("((m as { kind?: unknown }).kind === \"%s\" && is%s(m))", variant.Name, variant.Type))
+			}
+
+			check = fmt.Sprintf("typeof m === \"object\" && m !== null && (%s)", strings.Join(variants, " || "))
+		} else {
+			var ok bool
+			check, ok = tsCheck(member.Type, "m", 0, structs, scalars)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "WARNING! %s%s%s cannot be checked at runtime, accepting any value for %s.%s\n", member.Type.Prefix, member.Type.Value, member.Type.Suffix, s.Name, member.Name)
+				continue
+			}
+
+			if quoted, ok := tsJSONStringCheck(member, "m", scalars); ok {
+				check = check + " || " + quoted
+			}
+		}
+
+		if check == "true" {
+			// any value will do
+			continue
+		}
+
+		if (nullable || optional) && strings.Contains(check, " && ") {
+			check = "(" + check + ")"
+		}
+
+		if nullable {
+			check = "m === null || " + check
+		}
+
+		if optional {
+			check = "m === undefined || " + check
+		}
+
+		checks = append(checks, memberCheck{name: member.Name, check: check})
+	}
+
+	if len(checks) > 0 {
+		w.WriteString(fmt.Sprintf("\n%sconst v = value as { [key: string]: unknown };\n", inner))
+		w.WriteString(fmt.Sprintf("%slet m: unknown;\n", inner))
+	}
+
+	for _, c := range checks {
+		w.WriteString(fmt.Sprintf("\n%sm = v[%s];\n", inner, strconv.Quote(c.name)))
+		if strings.Contains(c.check, " ") {
+			w.WriteString(fmt.Sprintf("%sif (!(%s)) {\n", inner, c.check))
+		} else {
+			w.WriteString(fmt.Sprintf("%sif (!%s) {\n", inner, c.check))
+		}
+		w.WriteString(fmt.Sprintf("%s%sreturn false;\n", inner, inspecter.Indent))
+		w.WriteString(fmt.Sprintf("%s}\n", inner))
+	}
+
+	w.WriteString(fmt.Sprintf("\n%sreturn true;\n", inner))
+	w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+
+	w.WriteString(fmt.Sprintf("%s%sfunction assert%s(value: unknown): asserts value is %s {\n", indent, export, s.Name, s.Name))
+	w.WriteString(fmt.Sprintf("%sif (!is%s(value)) {\n", inner, s.Name))
+	w.WriteString(fmt.Sprintf("%s%sthrow new TypeError(\"value is not a %s\");\n", inner, inspecter.Indent, s.Name))
+	w.WriteString(fmt.Sprintf("%s}\n", inner))
+	w.WriteString(fmt.Sprintf("%s}\n\n", indent))
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGuardsUsePropertyNames(t *testing.T) {
+	input := writeInput(t, `package example
+
+type User struct {
+	ID     int    `+"`json:\"id\"`"+`
+	Name   string `+"`json:\"full_name,omitempty\"`"+`
+	Secret string `+"`json:\"-\"`"+`
+	Plain  bool
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Guards: true}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := files[0].Content
+	for _, read := range []string{`m = v["ID"];`, `m = v["Name"];`, `m = v["Secret"];`, `m = v["Plain"];`} {
+		if !strings.Contains(content, read) {
+			t.Errorf("isUser does not check %s", read)
+		}
+	}
+
+	for _, read := range []string{`v["id"]`, `v["full_name"]`} {
+		if strings.Contains(content, read) {
+			t.Errorf("isUser checks %s, which the interface does not declare", read)
+		}
+	}
+}
+
+func TestGuardsAcceptJSONStrings(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Invoice struct {
+	InvoiceID int64   `+"`json:\"invoiceID,string\"`"+`
+	Paid      bool    `+"`json:\"paid,string\"`"+`
+	Total     float64 `+"`json:\"total\"`"+`
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Guards: true}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "Invoice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := files[0].Content
+	for _, check := range []string{
+		`if (!(typeof m === "number" || (typeof m === "string" && /^-?[0-9]+(?:\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$/.test(m)))) {`,
+		`if (!(typeof m === "boolean" || m === "true" || m === "false")) {`,
+		`if (!(typeof m === "number")) {`,
+	} {
+		if !strings.Contains(content, check) {
+			t.Errorf("missing %s in:\n%s", check, content)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+)
+
+// isInt64 is whether a go type is a 64 bit integer, which javascript numbers
+// only hold exactly up to 2^53. int and uint are left out as they rarely
+// carry values that large.
+func isInt64(goType string) bool {
+	return goType == "int64" || goType == "uint64"
+}
+
+// int64Type returns the typescript type of a 64 bit integer under the Int64
+// policy, the string policy only applies to members with the ,string json option
+func (ts *TypescriptConverter) int64Type(jsonString bool) string {
+	switch ts.Int64 {
+	case "bigint":
+		return "bigint"
+	case "string":
+		if jsonString {
+			return "string"
+		}
+	}
+
+	return "number"
+}
+
+// bigint is whether a value is a 64 bit integer represented as a bigint
+func (ts *TypescriptConverter) bigint(vt *ValueType) bool {
+	return ts.Int64 == "bigint" && (vt.Kind == KindInt || vt.Kind == KindUint) && isInt64(basicGoType(vt))
+}
+
+// int64Value turns a 64 bit integer member with the ,string json option into
+// a string value under the string policy, keeping its basic go type to tell
+// it apart from go strings
+func (ts *TypescriptConverter) int64Value(member StructMember, vt *ValueType) *ValueType {
+	if ts.Int64 == "string" && member.JSONString && (vt.Kind == KindInt || vt.Kind == KindUint) && isInt64(basicGoType(vt)) {
+		return &ValueType{Kind: KindString, GoType: basicGoType(vt)}
+	}
+
+	return vt
+}
+
+// int64Types validates the Int64 policy, warns about the 64 bit integers
+// that are emitted as plain numbers and applies the policy to the member
+// types the interfaces and guards use
+func (ts *TypescriptConverter) int64Types(inspecter *Inspecter) error {
+	switch ts.Int64 {
+	case "", "number", "bigint", "string":
+	default:
+		return fmt.Errorf("unknown int64 policy %q, expected number, bigint or string", ts.Int64)
+	}
+
+	for i := range inspecter.Structs {
+		s := &inspecter.Structs[i]
+		if !ts.emits(*s) {
+			continue
+		}
+
+		for j := range s.Members {
+			member := &s.Members[j]
+
+			// encoding/json writes map keys as strings, only the values can lose precision
+			t := &member.Type
+			if member.Type.IsMap {
+				t = member.Type.MapVal
+			}
+
+			if t == nil {
+				continue
+			}
+
+			// ,string only applies to plain values and pointers to them
+			jsonString := member.JSONString && !member.Type.IsArray && !member.Type.IsMap
+
+			// named scalar types are declared once with the policy, only the
+			// string policy turns their ,string members into strings
+			if scalar := inspecter.findGoScalar(t.GoValue); scalar != nil && t.Value == scalar.Name {
+				if isInt64(scalar.GoType) && jsonString && ts.Int64 == "string" {
+					t.Value = "string"
+				}
+				continue
+			}
+
+			if t.Value != "number" || !isInt64(t.GoValue) {
+				continue
+			}
+
+			value := ts.int64Type(jsonString)
+			switch {
+			case value == "number" && jsonString:
+				fmt.Fprintf(os.Stderr, "WARNING! the %[3]s %[1]s.%[2]s is emitted as number, values above 2^53 lose precision, use --int64 string or bigint\n", s.Name, member.Name, t.GoValue)
+			case value == "number":
+				fmt.Fprintf(os.Stderr, "WARNING! the %[3]s %[1]s.%[2]s is emitted as number, values above 2^53 lose precision, add the ,string json option and use --int64 string or bigint\n", s.Name, member.Name, t.GoValue)
+			case value == "bigint" && !jsonString:
+				fmt.Fprintf(os.Stderr, "WARNING! the %[3]s %[1]s.%[2]s is encoded as a json number, JSON.parse loses precision above 2^53 before it becomes a bigint, add the ,string json option\n", s.Name, member.Name, t.GoValue)
+			}
+
+			t.Value = value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,354 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tsScalarsModule holds the branded scalar types the split modules share
+const tsScalarsModule = "scalars"
+
+var tsModuleIdentRegexp = regexp.MustCompile(`[^\pL\pN_$]`)
+
+// moduleOf returns the module the types of a go package and input file are
+// generated in when splitting the output
+func (ts *TypescriptConverter) moduleOf(pkg string, source string) string {
+	if ts.Split == "file" {
+		return strings.TrimSuffix(filepath.Base(source), ".go")
+	}
+
+	return pkg
+}
+
+func (ts *TypescriptConverter) moduleName(s Struct) string {
+	return ts.moduleOf(s.Package, s.Source)
+}
+
+// emits is whether the module being built declares a struct, every struct
+// when the output is not split
+func (ts *TypescriptConverter) emits(s Struct) bool {
+	return ts.module == "" || ts.moduleName(s) == ts.module
+}
+
+func (ts *TypescriptConverter) emitsScalar(scalar Scalar) bool {
+	return ts.module == "" || ts.moduleOf(scalar.Package, scalar.Source) == ts.module
+}
+
+// scalarRef returns the named scalar type a go type used by a member of from
+// refers to, resolved like structRef
+func (ts *TypescriptConverter) scalarRef(inspecter *Inspecter, from Struct, goValue string) *Scalar {
+	if goValue == "" || strings.Contains(goValue, ".") {
+		return inspecter.findGoScalar(goValue)
+	}
+
+	return inspecter.findGoScalar(from.Package + "." + goValue)
+}
+
+// structRef returns the struct a go type used by a member of from refers to,
+// bare names are structs of the same package and pkg.Name of another one
+func (ts *TypescriptConverter) structRef(inspecter *Inspecter, from Struct, goValue string) *Struct {
+	if goValue == "" {
+		return nil
+	}
+
+	if strings.Contains(goValue, ".") {
+		return inspecter.findGoStruct(goValue)
+	}
+
+	for i := range inspecter.Structs {
+		if inspecter.Structs[i].GoName == goValue && inspecter.Structs[i].Package == from.Package {
+			return &inspecter.Structs[i]
+		}
+	}
+
+	return nil
+}
+
+// moduleImports writes the imports of the structs the module being built
+// uses from other modules, returning whether it wrote any
+func (ts *TypescriptConverter) moduleImports(w *strings.Builder, inspecter *Inspecter) bool {
+	if ts.module == "" {
+		return false
+	}
+
+	var modules []string
+	names := make(map[string][]string)
+	scalars := make(map[string][]string)
+	seen := make(map[string]bool)
+
+	for _, s := range inspecter.Structs {
+		if !ts.emits(s) {
+			continue
+		}
+
+		for _, member := range s.Members {
+			goValues := []string{member.Type.GoValue}
+			if member.Type.IsMap && member.Type.MapVal != nil {
+				goValues = []string{member.Type.MapVal.GoValue}
+			}
+			for _, variant := range member.OneOf {
+				goValues = append(goValues, variant.Name)
+			}
+
+			for _, goValue := range goValues {
+				if scalar := ts.scalarRef(inspecter, s, goValue); scalar != nil {
+					if ts.emitsScalar(*scalar) || seen[scalar.Name] {
+						continue
+					}
+					seen[scalar.Name] = true
+
+					module := ts.moduleOf(scalar.Package, scalar.Source)
+					if _, ok := names[module]; !ok {
+						modules = append(modules, module)
+						names[module] = nil
+					}
+					scalars[module] = append(scalars[module], scalar.Name)
+					continue
+				}
+
+				ref := ts.structRef(inspecter, s, goValue)
+				if ref == nil || ts.emits(*ref) || seen[ref.Name] {
+					continue
+				}
+				seen[ref.Name] = true
+
+				module := ts.moduleName(*ref)
+				if _, ok := names[module]; !ok {
+					modules = append(modules, module)
+				}
+				names[module] = append(names[module], ref.Name)
+			}
+		}
+	}
+
+	if ts.usesISODateString(inspecter) {
+		w.WriteString(fmt.Sprintf("import type { %s } from \"./%s\";\n", tsISODateString, tsScalarsModule))
+	}
+
+	for _, module := range modules {
+		var types []string
+		var values []string
+
+		// the constructors of brands are values
+		if ts.Brands && !ts.Declaration {
+			values = append(values, scalars[module]...)
+		} else {
+			types = append(types, scalars[module]...)
+		}
+
+		for _, ref := range names[module] {
+			if ts.Style == "class" && !ts.Declaration {
+				// classes are values, fromJSON revives the nested ones
+				values = append(values, ref)
+				continue
+			}
+
+			types = append(types, ref)
+			if ts.Zod {
+				values = append(values, zodSchemaName(ref))
+			}
+			if ts.Guards {
+				values = append(values, "is"+ref)
+			}
+		}
+
+		sort.Strings(types)
+		sort.Strings(values)
+
+		if len(types) > 0 {
+			w.WriteString(fmt.Sprintf("import type { %s } from \"./%s\";\n", strings.Join(types, ", "), module))
+		}
+		if len(values) > 0 {
+			w.WriteString(fmt.Sprintf("import { %s } from \"./%s\";\n", strings.Join(values, ", "), module))
+		}
+	}
+
+	return len(modules) > 0 || ts.usesISODateString(inspecter)
+}
+
+// imports returns the typescript imports of the module being built, the
+// --import flags and the ts.import directives of its own input files
+func (ts *TypescriptConverter) imports(inspecter *Inspecter) []string {
+	if ts.module == "" {
+		return inspecter.Comments.TypescriptImports
+	}
+
+	var imports []string
+	for _, imp := range inspecter.Comments.TypescriptImports {
+		directive := false
+		own := false
+		for _, file := range inspecter.Comments.Files {
+			for _, other := range file.TypescriptImports {
+				if other == imp {
+					directive = true
+					own = own || ts.moduleOf(file.Package, file.Source) == ts.module
+				}
+			}
+		}
+
+		if !directive || own {
+			imports = append(imports, imp)
+		}
+	}
+
+	return imports
+}
+
+// declaredNames returns the names of the structs and named scalar types in declaration order
+func (ts *TypescriptConverter) declaredNames(inspecter *Inspecter) []string {
+	var names []string
+	for _, s := range inspecter.Structs {
+		names = append(names, s.Name)
+	}
+	for _, scalar := range inspecter.Scalars {
+		names = append(names, scalar.Name)
+	}
+
+	return names
+}
+
+// BuildFiles generates a single file unless Split is set, then every go
+// package or input file becomes an es module importing the structs it uses
+// from the others and an index module re-exports all of them
+func (ts *TypescriptConverter) BuildFiles(name string, inspecter *Inspecter) ([]OutputFile, error) {
+	extension := ts.FileExtension()
+
+	if ts.Split == "" {
+		w := new(strings.Builder)
+		err := ts.Builder(w, inspecter)
+		if err != nil {
+			return nil, err
+		}
+
+		files := []OutputFile{{Name: fmt.Sprintf("%s.%s", name, extension), Content: w.String()}}
+		if !ts.Factories {
+			return files, nil
+		}
+
+		factories, err := ts.factories(inspecter, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(files, OutputFile{Name: fmt.Sprintf("%s.%s.%s", name, tsFactoriesModule, extension), Content: factories}), nil
+	}
+
+	switch ts.Split {
+	case "package", "file":
+	default:
+		return nil, fmt.Errorf("unknown split %q, expected package or file", ts.Split)
+	}
+
+	if ts.Namespace != "" {
+		return nil, errors.New("split modules cannot be combined with a namespace, drop one of them")
+	}
+
+	var modules []string
+	sources := make(map[string]string)
+	declaredBy := make(map[string][]string)
+
+	for _, s := range inspecter.Structs {
+		module := ts.moduleName(s)
+		if module == "index" || module == tsScalarsModule || (ts.Factories && module == tsFactoriesModule) {
+			return nil, fmt.Errorf("%s is a reserved module name, rename the package or file declaring %s", module, s.GoName)
+		}
+
+		source, ok := sources[module]
+		if !ok {
+			modules = append(modules, module)
+			sources[module] = s.Source
+		} else if ts.Split == "file" && source != s.Source {
+			return nil, fmt.Errorf("%s and %s would both become the %s module", source, s.Source, module)
+		}
+
+		known := false
+		for _, other := range declaredBy[s.Name] {
+			known = known || other == module
+		}
+		if !known {
+			declaredBy[s.Name] = append(declaredBy[s.Name], module)
+		}
+	}
+
+	for _, scalar := range inspecter.Scalars {
+		module := ts.moduleOf(scalar.Package, scalar.Source)
+		if module == "index" || module == tsScalarsModule || (ts.Factories && module == tsFactoriesModule) {
+			return nil, fmt.Errorf("%s is a reserved module name, rename the package or file declaring %s", module, scalar.Name)
+		}
+
+		if _, ok := sources[module]; !ok {
+			modules = append(modules, module)
+			sources[module] = scalar.Source
+		}
+
+		declaredBy[scalar.Name] = append(declaredBy[scalar.Name], module)
+	}
+
+	var files []OutputFile
+	for _, module := range modules {
+		split := *ts
+		split.Module = true
+		split.module = module
+
+		w := new(strings.Builder)
+		err := split.Builder(w, inspecter)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", module, err)
+		}
+
+		files = append(files, OutputFile{Name: fmt.Sprintf("%s.%s", module, extension), Content: w.String()})
+	}
+
+	barrel := new(strings.Builder)
+
+	if ts.usesISODateString(inspecter) {
+		scalars := *ts
+		scalars.Module = true
+
+		w := new(strings.Builder)
+		scalars.isoDateString(w, inspecter, "")
+		files = append(files, OutputFile{Name: fmt.Sprintf("%s.%s", tsScalarsModule, extension), Content: w.String()})
+
+		barrel.WriteString(fmt.Sprintf("export * from \"./%s\";\n", tsScalarsModule))
+	}
+
+	// export * leaves out names declared by several modules, those modules
+	// are re-exported as namespaces instead
+	namespaced := make(map[string]bool)
+	for _, name := range ts.declaredNames(inspecter) {
+		if len(declaredBy[name]) > 1 {
+			fmt.Fprintf(os.Stderr, "WARNING! %s is declared by the %s modules, index re-exports them as namespaces\n", name, strings.Join(declaredBy[name], " and "))
+			for _, module := range declaredBy[name] {
+				namespaced[module] = true
+			}
+			declaredBy[name] = nil
+		}
+	}
+
+	for _, module := range modules {
+		if namespaced[module] {
+			barrel.WriteString(fmt.Sprintf("export * as %s from \"./%s\";\n", tsModuleIdentRegexp.ReplaceAllString(module, "_"), module))
+		} else {
+			barrel.WriteString(fmt.Sprintf("export * from \"./%s\";\n", module))
+		}
+	}
+
+	files = append(files, OutputFile{Name: fmt.Sprintf("index.%s", extension), Content: barrel.String()})
+
+	if ts.Factories {
+		// test data stays out of the index
+		factories, err := ts.factories(inspecter, "")
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, OutputFile{Name: fmt.Sprintf("%s.%s", tsFactoriesModule, extension), Content: factories})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitModulesOnlyImportTheirOwnDirectives(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"order.go": `package order
+
+// #ts.import import moment from "moment";
+
+type Order struct {
+	ID int
+}
+`,
+		"user.go": `package order
+
+type User struct {
+	Name string
+}
+`,
+	}
+
+	var inputs []string
+	for name, source := range sources {
+		input := filepath.Join(dir, name)
+		if err := os.WriteFile(input, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, input)
+	}
+
+	inspecter := &Inspecter{
+		Converter: &TypescriptConverter{Split: "file"},
+		Indent:    "\t",
+		Comments:  Comments{TypescriptImports: []string{"lodash from 'lodash'"}},
+	}
+	files, err := inspecter.ConvertToFiles(inputs, "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]string)
+	for _, file := range files {
+		contents[file.Name] = file.Content
+	}
+
+	if !strings.Contains(contents["order.ts"], `import moment from "moment";`) {
+		t.Errorf("order.ts does not import its directive:\n%s", contents["order.ts"])
+	}
+	if strings.Contains(contents["user.ts"], "moment") {
+		t.Errorf("user.ts imports the directive of order.go:\n%s", contents["user.ts"])
+	}
+	for _, name := range []string{"order.ts", "user.ts"} {
+		if !strings.Contains(contents[name], "import lodash from 'lodash';") {
+			t.Errorf("%s does not import the --import flag:\n%s", name, contents[name])
+		}
+	}
+}
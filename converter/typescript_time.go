@@ -0,0 +1,140 @@
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tsISODateString is the branded string type of the ISODateString time mapping
+const tsISODateString = "ISODateString"
+
+// tsZeroTime is the RFC 3339 encoding of the go zero time
+const tsZeroTime = `"0001-01-01T00:00:00Z"`
+
+var tsCallableRegexp = regexp.MustCompile(`^[\pL_$][\pL\pN_$.]*$`)
+
+// tsCall returns the call of a parse or format function, wrapping arrow
+// functions and other expressions in parens
+func tsCall(fn string, expr string) string {
+	if tsCallableRegexp.MatchString(fn) {
+		return fmt.Sprintf("%s(%s)", fn, expr)
+	}
+
+	return fmt.Sprintf("(%s)(%s)", fn, expr)
+}
+
+// timeType returns the typescript type of time.Time. Classes default to Date
+// and everything else to the RFC 3339 string encoding/json writes.
+func (ts *TypescriptConverter) timeType() string {
+	if ts.Time != "" {
+		return ts.Time
+	}
+
+	if ts.Style == "class" {
+		return "Date"
+	}
+
+	return "string"
+}
+
+// userTime is whether time.Time maps to a user type such as moment.Moment
+func (ts *TypescriptConverter) userTime() bool {
+	switch ts.timeType() {
+	case "string", "Date", tsISODateString:
+		return false
+	}
+
+	return true
+}
+
+// durationType returns the typescript type of time.Duration, the int64
+// nanoseconds encoding/json writes unless a user type is set
+func (ts *TypescriptConverter) durationType() string {
+	if ts.Duration != "" {
+		return ts.Duration
+	}
+
+	return "number"
+}
+
+// userDuration is whether a value is a time.Duration mapped to a user type
+func (ts *TypescriptConverter) userDuration(vt *ValueType) bool {
+	return vt.GoType == "time.Duration" && ts.durationType() != "number"
+}
+
+// checkTime makes sure the user time and duration types can be decoded by
+// the classes and zod schemas and created by the factories
+func (ts *TypescriptConverter) checkTime() error {
+	if ts.Style != "class" && !ts.Zod && !ts.Factories {
+		return nil
+	}
+
+	if ts.userTime() && ts.TimeParse == "" {
+		return fmt.Errorf("time type %s needs --time-parse to decode the RFC 3339 strings", ts.Time)
+	}
+
+	if ts.durationType() != "number" && (ts.DurationParse == "" || ts.DurationFormat == "") {
+		return fmt.Errorf("duration type %s needs --duration-parse and --duration-format to convert from and to nanoseconds", ts.Duration)
+	}
+
+	return nil
+}
+
+// memberValueType classifies a member like MemberValueType, taking
+// time.Duration as the int64 nanoseconds encoding/json writes
+func (ts *TypescriptConverter) memberValueType(inspecter *Inspecter, member StructMember) (*ValueType, error) {
+	vt, err := inspecter.MemberValueType(member)
+	if err == nil || member.GoType.IsMap || member.GoType.GoValue != "time.Duration" {
+		return vt, err
+	}
+
+	vt = &ValueType{Kind: KindInt, Size: 8, GoType: "time.Duration"}
+	if member.GoType.IsArray {
+		vt = &ValueType{Kind: KindSlice, GoType: "[]" + vt.GoType, Elem: vt}
+	}
+	if member.GoType.IsPointer {
+		vt = &ValueType{Kind: KindPointer, GoType: "*" + vt.GoType, Elem: vt}
+	}
+
+	return vt, nil
+}
+
+// usesISODateString is whether any member is declared as an ISODateString
+func (ts *TypescriptConverter) usesISODateString(inspecter *Inspecter) bool {
+	for _, s := range inspecter.Structs {
+		if !ts.emits(s) {
+			continue
+		}
+
+		for _, member := range s.Members {
+			t := member.Type
+			if t.IsMap && t.MapVal != nil {
+				t = *t.MapVal
+			}
+
+			if t.Value == tsISODateString {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isoDateString declares the ISODateString brand when a member uses it
+func (ts *TypescriptConverter) isoDateString(w *strings.Builder, inspecter *Inspecter, indent string) {
+	if ts.module != "" || !ts.usesISODateString(inspecter) {
+		// split modules import it from the scalars module
+		return
+	}
+
+	w.WriteString(fmt.Sprintf("%s/** an RFC 3339 time as encoding/json writes time.Time */\n", indent))
+	w.WriteString(indent)
+	if ts.exported() {
+		w.WriteString("export ")
+	} else {
+		w.WriteString("declare ")
+	}
+	w.WriteString(fmt.Sprintf("type %s = string & { readonly __brand: \"%s\" };\n\n", tsISODateString, tsISODateString))
+}
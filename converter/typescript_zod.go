@@ -0,0 +1,669 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// zodStructs orders the structs so schemas are declared before the schemas
+// using them. References to a schema declared later, such as the ones of
+// recursive structs, go through z.lazy and need a declared type because
+// typescript cannot infer it; their names are returned as lazy.
+func zodStructs(inspecter *Inspecter) ([]Struct, map[string]bool) {
+	byName := make(map[string]Struct)
+	names := make(map[string]bool)
+	for _, s := range inspecter.Structs {
+		byName[s.Name] = s
+		names[s.Name] = true
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	state := make(map[string]int)
+	lazy := make(map[string]bool)
+	var ordered []Struct
+
+	var visit func(s Struct)
+	visit = func(s Struct) {
+		state[s.Name] = visiting
+
+		for _, member := range s.Members {
+			refs := referencedStructs(member.Type, names)
+			for _, variant := range member.OneOf {
+				refs = append(refs, variant.Type)
+			}
+
+			for _, ref := range refs {
+				switch state[ref] {
+				case visiting:
+					lazy[ref] = true
+				case done:
+				default:
+					if _, ok := byName[ref]; ok {
+						visit(byName[ref])
+					}
+				}
+			}
+		}
+
+		state[s.Name] = done
+		ordered = append(ordered, s)
+	}
+
+	for _, s := range inspecter.Structs {
+		if state[s.Name] == 0 {
+			visit(s)
+		}
+	}
+
+	return ordered, lazy
+}
+
+func zodSchemaName(name string) string {
+	return name + "Schema"
+}
+
+// zodKey quotes json names that are not valid identifiers
+func zodKey(name string) string {
+	if TypescriptValidJSName(name) {
+		return name
+	}
+
+	return strconv.Quote(name)
+}
+
+// zodSchema returns the schema of a value as encoding/json encodes it
+func (ts *TypescriptConverter) zodSchema(vt *ValueType, declared map[string]bool) string {
+	if ts.branded(vt) {
+		basic := *vt
+		basic.Scalar = nil
+		return ts.zodSchema(&basic, declared) + ts.zodTransform(vt)
+	}
+
+	if ts.bigint(vt) {
+		return zodBigint(zodBigintSchema(vt))
+	}
+
+	if ts.userDuration(vt) {
+		return "z.number().int()" + ts.zodTransform(vt)
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return "z.boolean()"
+	case KindInt:
+		return "z.number().int()"
+	case KindUint:
+		return "z.number().int().nonnegative()"
+	case KindFloat:
+		return "z.number()"
+	case KindString:
+		if isInt64(vt.GoType) {
+			return "z.string().regex(/^-?[0-9]+$/)"
+		}
+		// []byte is a base64 string
+		return "z.string()"
+	case KindTime:
+		return "z.string().datetime({ offset: true })" + ts.zodTransform(vt)
+	case KindStruct:
+		name := zodSchemaName(vt.Struct.Name)
+		if !declared[vt.Struct.Name] {
+			return fmt.Sprintf("z.lazy(() => %s)", name)
+		}
+		return name
+	case KindPointer:
+		return ts.zodSchema(vt.Elem, declared) + ".nullable()"
+	case KindSlice:
+		return fmt.Sprintf("z.array(%s)", ts.zodSchema(vt.Elem, declared))
+	case KindMap:
+		// json object keys are always strings
+		return fmt.Sprintf("z.record(z.string(), %s)", ts.zodSchema(vt.Elem, declared))
+	}
+
+	return "z.any()"
+}
+
+// zodType returns the typescript type zod infers for a value, used to declare
+// the types of the lazily referenced schemas
+func (ts *TypescriptConverter) zodType(vt *ValueType) string {
+	if vt.Scalar != nil {
+		return vt.Scalar.Name
+	}
+
+	if ts.bigint(vt) {
+		return "bigint"
+	}
+
+	if vt.GoType == "time.Duration" {
+		return ts.durationType()
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return "boolean"
+	case KindInt, KindUint, KindFloat:
+		return "number"
+	case KindString:
+		return "string"
+	case KindTime:
+		return ts.timeType()
+	case KindStruct:
+		return vt.Struct.Name
+	case KindPointer:
+		return ts.zodType(vt.Elem) + " | null"
+	case KindSlice:
+		elem := ts.zodType(vt.Elem)
+		if strings.Contains(elem, "|") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case KindMap:
+		return fmt.Sprintf("Record<string, %s>", ts.zodType(vt.Elem))
+	}
+
+	return "any"
+}
+
+// zodMember is a member of a z.object, with the value of pointers unwrapped
+// because their nullability depends on the nullability model
+type zodMember struct {
+	StructMember
+	Value *ValueType // nil for oneof members and values without a schema
+}
+
+func (ts *TypescriptConverter) zodMembers(inspecter *Inspecter, s Struct) []zodMember {
+	var members []zodMember
+
+	for _, member := range s.Members {
+		if member.JSONIgnore {
+			continue
+		}
+
+		if len(member.OneOf) > 0 {
+			members = append(members, zodMember{StructMember: member})
+			continue
+		}
+
+		vt, err := ts.memberValueType(inspecter, member)
+		if err != nil {
+			if member.GoType.GoValue != "interface{}" && member.GoType.GoValue != "any" {
+				fmt.Fprintf(os.Stderr, "WARNING! %s, using z.any() for %s.%s\n", err, s.Name, member.Name)
+			}
+			members = append(members, zodMember{StructMember: member})
+			continue
+		}
+
+		if vt.Kind == KindPointer {
+			vt = vt.Elem
+		}
+
+		members = append(members, zodMember{StructMember: member, Value: ts.int64Value(member, vt)})
+	}
+
+	return members
+}
+
+// zodOneOf returns the union of the oneof variants, each tagged with its kind
+func zodOneOf(member StructMember, declared map[string]bool) string {
+	var variants []string
+	for _, variant := range member.OneOf {
+		schema := zodSchemaName(variant.Type)
+		if !declared[variant.Type] {
+			schema = fmt.Sprintf("z.lazy(() => %s)", schema)
+		}
+
+		variants = append(variants, fmt.Sprintf("z.object({ kind: z.literal(%q) }).and(%s)", variant.Name, schema))
+	}
+
+	if len(variants) == 1 {
+		return variants[0]
+	}
+
+	return fmt.Sprintf("z.union([%s])", strings.Join(variants, ", "))
+}
+
+func zodNumber(s Struct, member StructMember, rule ValidateRule) (string, bool) {
+	if _, err := strconv.ParseFloat(rule.Param, 64); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING! validate rule %s=%s is not a number, skipping %s.%s\n", rule.Name, rule.Param, s.Name, member.Name)
+		return "", false
+	}
+
+	return rule.Param, true
+}
+
+func zodBigintSchema(vt *ValueType) string {
+	if vt.Kind == KindUint {
+		return "z.bigint().nonnegative()"
+	}
+
+	return "z.bigint()"
+}
+
+// zodTransform returns the transform turning the json value of a time, a
+// duration or a brand into its typescript type, empty when they are the same
+func (ts *TypescriptConverter) zodTransform(vt *ValueType) string {
+	if ts.branded(vt) {
+		return fmt.Sprintf(".transform((v) => %s(v))", vt.Scalar.Name)
+	}
+
+	if ts.userDuration(vt) {
+		return fmt.Sprintf(".transform((v) => %s)", tsCall(ts.DurationParse, "v"))
+	}
+
+	if vt.Kind != KindTime {
+		return ""
+	}
+
+	switch ts.timeType() {
+	case "string":
+		return ""
+	case "Date":
+		return ".transform((v) => new Date(v))"
+	case tsISODateString:
+		return fmt.Sprintf(".transform((v) => v as %s)", tsISODateString)
+	}
+
+	return fmt.Sprintf(".transform((v) => %s)", tsCall(ts.TimeParse, "v"))
+}
+
+// zodTransforms is whether the parsed types can differ from the json input
+func (ts *TypescriptConverter) zodTransforms(inspecter *Inspecter) bool {
+	if ts.Int64 == "bigint" || ts.timeType() != "string" || ts.durationType() != "number" || ts.Brands {
+		return true
+	}
+
+	// numbers and bools with the ,string json option are parsed from strings
+	for _, s := range inspecter.Structs {
+		if !ts.emits(s) {
+			continue
+		}
+
+		for _, member := range s.Members {
+			vt, err := ts.memberValueType(inspecter, member)
+			if err != nil || member.JSONIgnore {
+				continue
+			}
+			if vt.Kind == KindPointer {
+				vt = vt.Elem
+			}
+
+			if zodJSONString(member, ts.int64Value(member, vt), "") != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// zodBigint wraps a bigint schema so it parses the json number or string of
+// a 64 bit integer
+func zodBigint(schema string) string {
+	return fmt.Sprintf("z.union([z.string().regex(/^-?[0-9]+$/), z.number().int()]).transform((v) => BigInt(v)).pipe(%s)", schema)
+}
+
+// zodJSONString wraps the schema of a number or bool member with the ,string
+// json option, encoding/json writes its value quoted in a json string
+func zodJSONString(member StructMember, vt *ValueType, schema string) string {
+	if !member.JSONString || member.Type.IsArray || member.Type.IsMap {
+		return schema
+	}
+
+	switch vt.Kind {
+	case KindBool:
+		return fmt.Sprintf(`z.enum(["true", "false"]).transform((v) => v === "true").pipe(%s)`, schema)
+	case KindInt, KindUint, KindFloat:
+		return fmt.Sprintf("z.string().regex(/^-?[0-9]+(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$/).transform(Number).pipe(%s)", schema)
+	}
+
+	return schema
+}
+
+// zodBound returns the parameter of a numeric validate rule as a typescript
+// literal, bigint bounds must be integers
+func (ts *TypescriptConverter) zodBound(s Struct, member StructMember, vt *ValueType, rule ValidateRule) (string, bool) {
+	n, ok := zodNumber(s, member, rule)
+	if !ok || !ts.bigint(vt) {
+		return n, ok
+	}
+
+	if _, err := strconv.ParseInt(n, 10, 64); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING! validate rule %s=%s is not an integer, skipping %s.%s\n", rule.Name, rule.Param, s.Name, member.Name)
+		return "", false
+	}
+
+	return n + "n", true
+}
+
+// zodOneOfValues turns the space separated values of a oneof rule into a typescript array
+func (ts *TypescriptConverter) zodOneOfValues(vt *ValueType, param string) string {
+	var values []string
+	for _, value := range strings.Fields(param) {
+		if vt.Kind == KindString {
+			value = strconv.Quote(strings.Trim(value, "'"))
+		} else if ts.bigint(vt) {
+			value += "n"
+		}
+		values = append(values, value)
+	}
+
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// zodRules appends the refinements matching the validate rules of a member
+// to its schema. required is whether the rules forbid nil pointers, slices
+// and maps.
+func (ts *TypescriptConverter) zodRules(s Struct, member StructMember, vt *ValueType, schema string) (string, bool) {
+	var refinements []string
+	required := false
+	omitEmpty := false
+
+	for _, rule := range member.Validate {
+		if rule.Name == "-" {
+			return schema, false
+		}
+	}
+
+	// 64 bit integers kept as strings only tell zero from other values
+	int64String := vt.Kind == KindString && isInt64(vt.GoType)
+
+	zero := "0"
+	if ts.bigint(vt) {
+		zero = "0n"
+	}
+
+	for _, rule := range member.Validate {
+		refinement := ""
+
+		switch rule.Name {
+		case "omitempty":
+			omitEmpty = true
+			continue
+		case "required":
+			required = true
+			if member.Type.IsPointer {
+				// only rules out nil pointers
+				continue
+			}
+
+			switch {
+			case int64String:
+				refinement = `.refine((v) => v !== "0", { message: "required" })`
+			case vt.Kind == KindString:
+				if _, ok := member.ValidateBound("min", "gte", "len"); !ok {
+					refinement = ".min(1)"
+				}
+			case vt.Kind == KindInt, vt.Kind == KindUint, vt.Kind == KindFloat:
+				refinement = fmt.Sprintf(`.refine((v) => v !== %s, { message: "required" })`, zero)
+			}
+
+			if refinement == "" {
+				continue
+			}
+		}
+
+		if refinement == "" && int64String {
+			fmt.Fprintf(os.Stderr, "WARNING! validate rule %s is not checked on the %s string of %s.%s\n", rule.Name, vt.GoType, s.Name, member.Name)
+			continue
+		}
+
+		if refinement == "" {
+			switch vt.Kind {
+			case KindString:
+				switch rule.Name {
+				case "min", "gte", "max", "lte", "len":
+					if n, ok := zodNumber(s, member, rule); ok {
+						refinement = map[string]string{"min": ".min", "gte": ".min", "max": ".max", "lte": ".max", "len": ".length"}[rule.Name] + "(" + n + ")"
+					}
+				case "email":
+					refinement = ".email()"
+				case "url", "uri", "http_url":
+					refinement = ".url()"
+				case "uuid", "uuid4":
+					refinement = ".uuid()"
+				case "ip":
+					refinement = ".ip()"
+				case "ipv4":
+					refinement = `.ip({ version: "v4" })`
+				case "ipv6":
+					refinement = `.ip({ version: "v6" })`
+				case "alpha":
+					refinement = ".regex(/^[a-zA-Z]+$/)"
+				case "alphanum":
+					refinement = ".regex(/^[a-zA-Z0-9]+$/)"
+				case "numeric":
+					refinement = ".regex(/^[-+]?[0-9]+(?:\\.[0-9]+)?$/)"
+				case "startswith":
+					refinement = fmt.Sprintf(".startsWith(%q)", rule.Param)
+				case "endswith":
+					refinement = fmt.Sprintf(".endsWith(%q)", rule.Param)
+				case "contains":
+					refinement = fmt.Sprintf(".includes(%q)", rule.Param)
+				case "oneof":
+					refinement = fmt.Sprintf(".refine((v) => %s.includes(v), { message: %q })", ts.zodOneOfValues(vt, rule.Param), "must be one of "+rule.Param)
+				}
+			case KindInt, KindUint, KindFloat:
+				switch rule.Name {
+				case "min", "gte", "max", "lte", "gt", "lt":
+					if n, ok := ts.zodBound(s, member, vt, rule); ok {
+						refinement = map[string]string{"min": ".gte", "gte": ".gte", "max": ".lte", "lte": ".lte", "gt": ".gt", "lt": ".lt"}[rule.Name] + "(" + n + ")"
+					}
+				case "oneof":
+					refinement = fmt.Sprintf(".refine((v) => %s.includes(v), { message: %q })", ts.zodOneOfValues(vt, rule.Param), "must be one of "+rule.Param)
+				}
+			case KindSlice:
+				switch rule.Name {
+				case "min", "gte", "max", "lte", "len":
+					if n, ok := zodNumber(s, member, rule); ok {
+						refinement = map[string]string{"min": ".min", "gte": ".min", "max": ".max", "lte": ".max", "len": ".length"}[rule.Name] + "(" + n + ")"
+					}
+				}
+			case KindMap:
+				switch rule.Name {
+				case "min", "gte", "max", "lte", "len":
+					if n, ok := zodNumber(s, member, rule); ok {
+						op := map[string]string{"min": ">=", "gte": ">=", "max": "<=", "lte": "<=", "len": "==="}[rule.Name]
+						refinement = fmt.Sprintf(".refine((v) => Object.keys(v).length %s %s, { message: %q })", op, n, rule.Name+"="+n)
+					}
+				}
+			}
+		}
+
+		if refinement == "" {
+			fmt.Fprintf(os.Stderr, "WARNING! validate rule %s has no zod equivalent, skipping it for %s.%s\n", rule.Name, s.Name, member.Name)
+			continue
+		}
+
+		refinements = append(refinements, refinement)
+	}
+
+	if len(refinements) == 0 {
+		return schema, required
+	}
+
+	refined := schema + strings.Join(refinements, "")
+	if omitEmpty {
+		// omitempty skips the other rules for empty values
+		switch vt.Kind {
+		case KindString:
+			if int64String {
+				refined += `.or(z.literal("0"))`
+			} else {
+				refined += `.or(z.literal(""))`
+			}
+		case KindInt, KindUint, KindFloat:
+			refined += fmt.Sprintf(".or(z.literal(%s))", zero)
+		case KindSlice:
+			refined += ".or(z.tuple([]))"
+		}
+	}
+
+	return refined, required
+}
+
+// zodBuilder writes a zod schema and an inferred type for every struct
+func (ts *TypescriptConverter) zodBuilder(w *strings.Builder, inspecter *Inspecter) error {
+	if ts.Namespace != "" {
+		return errors.New("zod schemas are es modules and cannot be combined with a namespace")
+	}
+
+	if ts.Declaration {
+		return errors.New("zod schemas are values and cannot be generated as a declaration file")
+	}
+
+	if ts.Nullability == "" {
+		// the schemas validate what encoding/json produced
+		model := *ts
+		model.Nullability = "json"
+		ts = &model
+	}
+
+	indent := inspecter.Indent
+
+	w.WriteString("import { z } from \"zod\";\n")
+	for _, imports := range ts.imports(inspecter) {
+		w.WriteString(fmt.Sprintf("import %s;\n", imports))
+	}
+	ts.moduleImports(w, inspecter)
+	w.WriteString("\n")
+
+	ts.isoDateString(w, inspecter, "")
+	ts.scalars(w, inspecter, "")
+
+	structs, lazy := zodStructs(inspecter)
+	declared := make(map[string]bool)
+
+	for _, s := range structs {
+		if !ts.emits(s) {
+			continue
+		}
+
+		if ts.Constants {
+			ts.memberConstants(w, "", s)
+		}
+
+		members := ts.zodMembers(inspecter, s)
+
+		type entry struct {
+			member   zodMember
+			schema   string
+			optional bool
+			nullable bool
+		}
+
+		var entries []entry
+		for _, member := range members {
+			optional, nullable, err := ts.nullability(member.StructMember)
+			if err != nil {
+				return err
+			}
+
+			var schema string
+			switch {
+			case len(member.OneOf) > 0:
+				schema = zodOneOf(member.StructMember, declared)
+			case member.Value == nil:
+				schema = "z.any()"
+			default:
+				var required bool
+				if ts.bigint(member.Value) {
+					// the refinements apply to the parsed bigint
+					schema, required = ts.zodRules(s, member.StructMember, member.Value, zodBigintSchema(member.Value))
+					schema = zodBigint(schema) + ts.zodTransform(member.Value)
+				} else {
+					// the refinements apply to the json value before it is transformed
+					transform := ts.zodTransform(member.Value)
+					schema, required = ts.zodRules(s, member.StructMember, member.Value, strings.TrimSuffix(ts.zodSchema(member.Value, declared), transform))
+					schema = zodJSONString(member.StructMember, member.Value, schema) + transform
+				}
+				if required && (member.Type.IsPointer || member.Type.IsArray || member.Type.IsMap) {
+					optional = false
+					nullable = false
+				}
+			}
+
+			entries = append(entries, entry{member: member, schema: schema, optional: optional, nullable: nullable})
+		}
+
+		if lazy[s.Name] {
+			// typescript cannot infer the type of a schema referring to itself
+			w.WriteString(DocComment("", s.Comment, s.Deprecated))
+			w.WriteString(fmt.Sprintf("export interface %s {\n", s.Name))
+			for _, e := range entries {
+				var t string
+				switch {
+				case len(e.member.OneOf) > 0:
+					var variants []string
+					for _, variant := range e.member.OneOf {
+						variants = append(variants, fmt.Sprintf("({ kind: \"%s\" } & %s)", variant.Name, variant.Type))
+					}
+					t = strings.Join(variants, " | ")
+				case e.member.Value == nil:
+					t = "any"
+				default:
+					t = ts.zodType(e.member.Value)
+				}
+
+				if e.nullable {
+					t += " | null"
+				}
+
+				optional := ""
+				if e.optional {
+					optional = "?"
+				}
+
+				w.WriteString(DocComment(indent, e.member.Comment, e.member.Deprecated))
+				w.WriteString(fmt.Sprintf("%s%s%s: %s;\n", indent, zodKey(e.member.JSONName), optional, t))
+			}
+			w.WriteString("}\n\n")
+
+			if ts.zodTransforms(inspecter) {
+				// the json input differs from the parsed type
+				w.WriteString(fmt.Sprintf("export const %s: z.ZodType<%s, z.ZodTypeDef, unknown> = z.object({\n", zodSchemaName(s.Name), s.Name))
+			} else {
+				w.WriteString(fmt.Sprintf("export const %s: z.ZodType<%s> = z.object({\n", zodSchemaName(s.Name), s.Name))
+			}
+		} else {
+			w.WriteString(fmt.Sprintf("export const %s = z.object({\n", zodSchemaName(s.Name)))
+		}
+
+		for _, e := range entries {
+			schema := e.schema
+			switch {
+			case e.optional && e.nullable:
+				schema += ".nullish()"
+			case e.optional:
+				schema += ".optional()"
+			case e.nullable:
+				schema += ".nullable()"
+			}
+
+			w.WriteString(DocComment(indent, e.member.Comment, e.member.Deprecated))
+			w.WriteString(fmt.Sprintf("%s%s: %s,\n", indent, zodKey(e.member.JSONName), schema))
+		}
+		w.WriteString("});\n\n")
+
+		if !lazy[s.Name] {
+			w.WriteString(DocComment("", s.Comment, s.Deprecated))
+			w.WriteString(fmt.Sprintf("export type %s = z.infer<typeof %s>;\n\n", s.Name, zodSchemaName(s.Name)))
+		}
+
+		declared[s.Name] = true
+	}
+
+	if ts.TypeIDs {
+		err := ts.typeIDConstants(w, "", inspecter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZodParsesJSONStringNumbersAndBools(t *testing.T) {
+	input := writeInput(t, `package example
+
+type Counter struct {
+	N     int     `+"`json:\"n,string\" validate:\"min=1\"`"+`
+	Ratio float64 `+"`json:\"ratio,string\"`"+`
+	On    *bool   `+"`json:\"on,string\"`"+`
+	Plain int     `+"`json:\"plain\"`"+`
+}
+`)
+
+	inspecter := &Inspecter{Converter: &TypescriptConverter{Module: true, Zod: true}, Indent: "\t"}
+	files, err := inspecter.ConvertToFiles([]string{input}, "Counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := files[0].Content
+	for _, schema := range []string{
+		"n: z.string().regex(/^-?[0-9]+(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$/).transform(Number).pipe(z.number().int().gte(1)),",
+		"ratio: z.string().regex(/^-?[0-9]+(?:\\.[0-9]+)?(?:[eE][-+]?[0-9]+)?$/).transform(Number).pipe(z.number()),",
+		`on: z.enum(["true", "false"]).transform((v) => v === "true").pipe(z.boolean()).nullable(),`,
+		"plain: z.number().int(),",
+	} {
+		if !strings.Contains(content, schema) {
+			t.Errorf("missing %s in:\n%s", schema, content)
+		}
+	}
+}
@@ -1,36 +1,140 @@
 package converter
 
 import (
-	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
-// TODO unused currently
+// ValidateRule is a single rule of a go-playground style `validate:"required,min=2"` tag
+type ValidateRule struct {
+	Name  string
+	Param string
+}
+
+// ParseValidateTag returns the rules applying to the field itself. Rules
+// after dive apply to the elements and rules combined with | are left out.
+func ParseValidateTag(tag string) []ValidateRule {
+	var rules []ValidateRule
+
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "dive" {
+			break
+		}
+
+		if rule == "" || strings.Contains(rule, "|") {
+			continue
+		}
+
+		name, param, _ := strings.Cut(rule, "=")
+		rules = append(rules, ValidateRule{Name: name, Param: param})
+	}
+
+	return rules
+}
+
+// ValidateRule returns the parameter of the first of the named validate rules the member has
+func (member StructMember) ValidateRule(names ...string) (string, bool) {
+	for _, name := range names {
+		for _, rule := range member.Validate {
+			if rule.Name == name {
+				return rule.Param, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// ValidateBound returns a numeric validate parameter, such as the 32 of max=32
+func (member StructMember) ValidateBound(names ...string) (string, bool) {
+	param, ok := member.ValidateRule(names...)
+	if !ok {
+		return "", false
+	}
+
+	if _, err := strconv.ParseFloat(param, 64); err != nil {
+		return "", false
+	}
+
+	return param, true
+}
+
+// Constant is a named number describing a struct member
+type Constant struct {
+	Name  string
+	Value string
+}
 
-// NumberValidator performs numerical value validation.
-// Its limited to int type for simplicity.
-type Validator struct {
-	Type string
-	Min  int
-	Max  int
+var constantInvalidRe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// ConstantCase turns a name like OrderItem into ORDER_ITEM
+func ConstantCase(s string) string {
+	var b strings.Builder
+
+	runes := []rune(s)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return constantInvalidRe.ReplaceAllString(b.String(), "_")
+}
+
+// MemberConstants returns the capacity of the fixed size c array declared by
+// a ctype tag (STRUCT_MEMBER_MAX) and the numeric bounds of validate tags
+// (STRUCT_MEMBER_VALIDATE_MIN, _MAX and _LEN)
+func MemberConstants(s Struct, member StructMember) []Constant {
+	var constants []Constant
+	prefix := ConstantCase(s.Name) + "_" + ConstantCase(member.Name)
+
+	if capacity, ok := cTagCapacity(member); ok {
+		constants = append(constants, Constant{Name: prefix + "_MAX", Value: strconv.Itoa(capacity)})
+	}
+
+	if min, ok := member.ValidateBound("min", "gte"); ok {
+		constants = append(constants, Constant{Name: prefix + "_VALIDATE_MIN", Value: min})
+	}
+
+	if max, ok := member.ValidateBound("max", "lte"); ok {
+		constants = append(constants, Constant{Name: prefix + "_VALIDATE_MAX", Value: max})
+	}
+
+	if length, ok := member.ValidateBound("len"); ok {
+		constants = append(constants, Constant{Name: prefix + "_VALIDATE_LEN", Value: length})
+	}
+
+	return constants
 }
 
-// Returns validator struct corresponding to validation type
-func getValidatorFromTag(tag string) (bool, Validator) {
-	args := strings.Split(tag, ",")
+// cTagCapacity returns the number of elements of the c array declared by a ctype tag such as `char[255]`
+func cTagCapacity(member StructMember) (int, bool) {
+	if member.Tags == nil {
+		return 0, false
+	}
+
+	cTypeTag, err := member.Tags.Get("ctype")
+	if err != nil {
+		return 0, false
+	}
+
+	idx := strings.Index(cTypeTag.Name, "[")
+	if idx <= 0 {
+		return 0, false
+	}
 
-	switch args[0] {
-	case "number":
-		validator := Validator{Type: "number"}
-		fmt.Sscanf(strings.Join(args[1:], ","), "min=%d,max=%d", &validator.Min, &validator.Max)
-		return true, validator
-	case "string":
-		validator := Validator{Type: "string"}
-		fmt.Sscanf(strings.Join(args[1:], ","), "min=%d,max=%d", &validator.Min, &validator.Max)
-		return true, validator
-	case "email":
-		return true, Validator{Type: "email"}
+	count, err := cArrayCount(cTypeTag.Name[idx:])
+	if err != nil || count == 0 {
+		return 0, false
 	}
 
-	return false, Validator{}
+	return count, true
 }
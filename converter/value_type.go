@@ -0,0 +1,238 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValueKind is the kind of go value a struct member holds, used by the
+// generators that emit code reading and writing the members
+type ValueKind int
+
+const (
+	KindBool ValueKind = iota
+	KindInt
+	KindUint
+	KindFloat
+	KindString // string or []byte
+	KindTime   // time.Time
+	KindStruct
+	KindPointer
+	KindSlice
+	KindMap
+)
+
+type ValueType struct {
+	Kind   ValueKind
+	Size   int        // width in bytes of numbers
+	GoType string     // the go type of the value, e.g. int32, []byte or *Alias
+	Struct *Struct    // the struct of KindStruct values
+	Key    *ValueType // the key of KindMap values
+	Elem   *ValueType // the element of KindPointer and KindSlice values and the value of KindMap values
+	Fixed  int        // capacity of the c array declared by a ctype tag, 0 when dynamically allocated
+	Scalar *Scalar    // the named scalar type of the value, nil for basic types
+	Basic  string     // the basic go type of named scalar types, e.g. int64 for UserID
+}
+
+var goNumbers = map[string]ValueType{
+	"bool":    {Kind: KindBool, Size: 1},
+	"int8":    {Kind: KindInt, Size: 1},
+	"int16":   {Kind: KindInt, Size: 2},
+	"int32":   {Kind: KindInt, Size: 4},
+	"rune":    {Kind: KindInt, Size: 4},
+	"int64":   {Kind: KindInt, Size: 8},
+	"int":     {Kind: KindInt, Size: 8},
+	"byte":    {Kind: KindUint, Size: 1},
+	"uint8":   {Kind: KindUint, Size: 1},
+	"uint16":  {Kind: KindUint, Size: 2},
+	"uint32":  {Kind: KindUint, Size: 4},
+	"uint64":  {Kind: KindUint, Size: 8},
+	"uint":    {Kind: KindUint, Size: 8},
+	"float32": {Kind: KindFloat, Size: 4},
+	"float64": {Kind: KindFloat, Size: 8},
+}
+
+// findGoScalar finds a named scalar type like findGoStruct finds structs
+func (inspecter *Inspecter) findGoScalar(name string) *Scalar {
+	pkg := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		pkg, name = name[:i], name[i+1:]
+	}
+
+	for i := range inspecter.Scalars {
+		if inspecter.Scalars[i].Name == name && (pkg == "" || inspecter.Scalars[i].Package == pkg) {
+			return &inspecter.Scalars[i]
+		}
+	}
+
+	return nil
+}
+
+// findGoStruct finds a struct by its go name, pkg.Name being a struct of
+// another input package
+func (inspecter *Inspecter) findGoStruct(name string) *Struct {
+	pkg := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		pkg, name = name[:i], name[i+1:]
+	}
+
+	for i := range inspecter.Structs {
+		if inspecter.Structs[i].GoName == name && (pkg == "" || inspecter.Structs[i].Package == pkg) {
+			return &inspecter.Structs[i]
+		}
+	}
+
+	return nil
+}
+
+// ValueType classifies a go type, returning an error for types no generator understands
+func (inspecter *Inspecter) ValueType(t StructMemberType) (*ValueType, error) {
+	if t.IsMap {
+		if t.MapKey == nil || t.MapVal == nil {
+			return nil, errors.New("unknown map type")
+		}
+
+		key, err := inspecter.ValueType(*t.MapKey)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.Kind {
+		case KindString, KindInt, KindUint:
+		default:
+			return nil, fmt.Errorf("map keys of %s are unsupported", key.GoType)
+		}
+
+		elem, err := inspecter.ValueType(*t.MapVal)
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.Kind == KindMap {
+			return nil, errors.New("maps of maps are unsupported")
+		}
+
+		return &ValueType{Kind: KindMap, GoType: fmt.Sprintf("map[%s]%s", key.GoType, elem.GoType), Key: key, Elem: elem}, nil
+	}
+
+	if t.IsPointer {
+		t.IsPointer = false
+		if t.IsArray {
+			return nil, errors.New("pointers to slices are unsupported")
+		}
+
+		elem, err := inspecter.ValueType(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if elem.Kind == KindStruct && elem.Struct.Anonymous {
+			return nil, errors.New("pointers to anonymous structs are unsupported")
+		}
+
+		return &ValueType{Kind: KindPointer, GoType: "*" + elem.GoType, Elem: elem}, nil
+	}
+
+	if t.IsArray {
+		t.IsArray = false
+		elem, err := inspecter.ValueType(t)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ValueType{Kind: KindSlice, GoType: "[]" + elem.GoType, Elem: elem}, nil
+	}
+
+	if number, ok := goNumbers[t.GoValue]; ok {
+		number.GoType = t.GoValue
+		return &number, nil
+	}
+
+	switch t.GoValue {
+	case "string", "[]byte":
+		return &ValueType{Kind: KindString, GoType: t.GoValue}, nil
+	case "time.Time":
+		return &ValueType{Kind: KindTime, Size: 8, GoType: t.GoValue}, nil
+	}
+
+	if s := inspecter.findGoStruct(t.GoValue); s != nil {
+		return &ValueType{Kind: KindStruct, GoType: s.GoName, Struct: s}, nil
+	}
+
+	if scalar := inspecter.findGoScalar(t.GoValue); scalar != nil {
+		basic, err := inspecter.ValueType(StructMemberType{GoValue: scalar.GoType})
+		if err != nil {
+			return nil, err
+		}
+
+		switch basic.Kind {
+		case KindBool, KindInt, KindUint, KindFloat, KindString:
+		default:
+			return nil, fmt.Errorf("%s of %s is unsupported", t.GoValue, scalar.GoType)
+		}
+
+		named := *basic
+		named.GoType = t.GoValue
+		named.Scalar = scalar
+		if basic.Basic == "" {
+			named.Basic = basic.GoType
+		}
+
+		return &named, nil
+	}
+
+	return nil, fmt.Errorf("%s is unsupported", t.GoValue)
+}
+
+// MemberValueType classifies the go type of a struct member, taking the
+// fixed size c arrays declared by ctype tags into account
+func (inspecter *Inspecter) MemberValueType(member StructMember) (*ValueType, error) {
+	if member.GoType.GoValue == "" && !member.GoType.IsMap {
+		return nil, errors.New("unknown go type")
+	}
+
+	bt, err := inspecter.ValueType(member.GoType)
+	if err != nil {
+		return nil, err
+	}
+
+	if member.Tags == nil {
+		return bt, nil
+	}
+
+	cTypeTag, err := member.Tags.Get("ctype")
+	if err != nil {
+		return bt, nil
+	}
+
+	// a ctype tag can turn strings and slices into fixed size c arrays
+	fixed := 0
+	if idx := strings.Index(cTypeTag.Name, "["); idx > 0 {
+		fixed, err = cArrayCount(cTypeTag.Name[idx:])
+		if err != nil || fixed == 0 {
+			return nil, fmt.Errorf("invalid ctype %q", cTypeTag.Name)
+		}
+	}
+
+	switch bt.Kind {
+	case KindString:
+		if !strings.HasPrefix(strings.TrimSpace(cTypeTag.Name), "char") {
+			return nil, fmt.Errorf("ctype %q cannot hold a string", cTypeTag.Name)
+		}
+		bt.Fixed = fixed
+	case KindSlice:
+		if fixed == 0 {
+			return nil, fmt.Errorf("ctype %q cannot hold a slice", cTypeTag.Name)
+		}
+		bt.Fixed = fixed
+	case KindStruct, KindPointer, KindMap:
+		return nil, fmt.Errorf("ctype %q is unsupported for %s", cTypeTag.Name, bt.GoType)
+	default:
+		if fixed != 0 {
+			return nil, fmt.Errorf("ctype %q cannot hold a single value", cTypeTag.Name)
+		}
+	}
+
+	return bt, nil
+}
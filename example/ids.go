@@ -0,0 +1,19 @@
+package order
+
+// UserID identifies a user
+type UserID int64
+
+// OrderID identifies an order, it cannot be passed where a UserID is expected
+type OrderID int64
+
+type Currency string
+
+// Account holds the ids of a user and its orders
+type Account struct {
+	ID       UserID             `json:"id,string"`
+	Orders   []OrderID          `json:"orders"`
+	Last     *OrderID           `json:"last,omitempty"`
+	Balances map[string]float64 `json:"balances"`
+	Currency Currency           `json:"currency" validate:"oneof=EUR USD"`
+	Referrer UserID             `json:"referrer"`
+}
@@ -0,0 +1,11 @@
+package order
+
+import "time"
+
+// Schedule runs a job every Interval from Start until the optional End
+type Schedule struct {
+	Start    time.Time     `json:"start"`
+	End      *time.Time    `json:"end,omitempty"`
+	Interval time.Duration `json:"interval" validate:"min=1000000000"`
+	Skipped  []time.Time   `json:"skipped"`
+}
@@ -0,0 +1,10 @@
+package order
+
+// Snowflake ids go past the 2^53 javascript numbers hold exactly
+type Snowflake struct {
+	ID       int64         `json:"id,string" validate:"required"`
+	ParentID *uint64       `json:"parentID,string,omitempty"`
+	Sequence int64         `json:"sequence,string" validate:"min=0,max=4095"`
+	Counts   map[int64]int `json:"counts"`
+	Worker   int           `json:"worker"`
+}
@@ -0,0 +1,28 @@
+package billing
+
+import (
+	"time"
+
+	order "github.com/steeringwaves/go-struct-convert/example"
+)
+
+// Invoice bills the orders of a user
+type Invoice struct {
+	InvoiceID int64          `json:"invoiceID,string"`
+	Customer  order.UserID   `json:"customer"`
+	User      order.User     `json:"user"`
+	Shipping  *order.State   `json:"shipping,omitempty"`
+	Lines     []Line         `json:"lines"`
+	Issued    time.Time      `json:"issued"`
+	Totals    map[string]Sum `json:"totals"`
+}
+
+type Line struct {
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+}
+
+type Sum struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
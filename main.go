@@ -19,8 +19,49 @@ var prefix string = ""
 var suffix string = ""
 var name string = ""
 var cIncludes []string
+var cPacked bool = false
+var cPack int = 0
+var cStaticAsserts bool = false
+var cPointerSize int = 8
+var cBoolSize int = 1
+var cBinary bool = false
+var cHeader string = ""
+var cJSON bool = false
+var cLifecycle bool = false
+var cDebug bool = false
+var cGuard string = "pragma"
+var cGuardName string = ""
+var cExternC bool = false
+var cConstants bool = false
+var cSource bool = false
+var cTypeIDs bool = false
+var cTypeIDsName string = ""
+var cFields string = ""
+var cJSONInclude string = "\"cJSON.h\""
+var endian string = "little"
+var cppNamespace string = ""
+var cppJSON bool = false
 var tsNamespace string = ""
 var tsImports []string
+var tsConstants bool = false
+var tsTypeIDs bool = false
+var tsModule bool = false
+var tsStyle string = "interface"
+var tsDeclaration bool = false
+var tsNullability string = ""
+var tsZod bool = false
+var tsGuards bool = false
+var tsNullableSlices bool = false
+var tsInt64 string = "number"
+var tsTime string = ""
+var tsTimeParse string = ""
+var tsTimeFormat string = ""
+var tsDuration string = "number"
+var tsDurationParse string = ""
+var tsDurationFormat string = ""
+var tsSplit string = ""
+var tsBrands bool = false
+var tsFactories bool = false
 var indent string = "	"
 
 // var tsRequires []string
@@ -46,21 +87,63 @@ func doConversion(input []string, output string, inspecter *converter.Inspecter)
 		}
 	}
 
-	builder, err := inspecter.ConvertFiles(input)
+	files, err := inspecter.ConvertToFiles(input, output)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	if useStdout {
-		fmt.Println(builder.String())
+	for _, file := range files {
+		if useStdout {
+			if len(files) > 1 {
+				// tell the files apart like head and tail do
+				fmt.Printf("==> %s <==\n", file.Name)
+			}
+			fmt.Println(file.Content)
+		}
+
+		err = ioutil.WriteFile(path.Join(dirname, file.Name), []byte(file.Content), 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
+}
 
-	err = ioutil.WriteFile(path.Join(dirname, fmt.Sprintf("%s.%s", output, inspecter.FileExtension())), []byte(builder.String()), 0644)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+// parseInputs collects the input files and returns the name of the output file without an extension
+func parseInputs(args []string) string {
+	if len(inputFiles) == 0 {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "no files specified")
+			os.Exit(1)
+		}
+
+		for i := range args {
+			inputFiles = append(inputFiles, args[i])
+		}
 	}
+
+	outputFilename := name
+	if outputFilename == "" {
+		outputFilename = strings.TrimSuffix(path.Base(inputFiles[0]), path.Ext(inputFiles[0]))
+	} else {
+		outputFilename = strings.TrimSuffix(path.Base(outputFilename), path.Ext(outputFilename))
+	}
+
+	return outputFilename
+}
+
+func isBigEndian() bool {
+	switch endian {
+	case "little":
+		return false
+	case "big":
+		return true
+	}
+
+	fmt.Fprintln(os.Stderr, "endian must be little or big")
+	os.Exit(1)
+	return false
 }
 
 var typescriptCmd = &cobra.Command{
@@ -68,27 +151,30 @@ var typescriptCmd = &cobra.Command{
 	Short: "Converts go structs to typescript",
 	Long:  `This command converts go structs to typescript`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(inputFiles) == 0 {
-			if len(args) == 0 {
-				fmt.Fprintln(os.Stderr, "no files specified")
-				os.Exit(1)
-			}
-
-			for i := range args {
-				inputFiles = append(inputFiles, args[i])
-			}
-		}
-
-		outputFilename := name
-		if outputFilename == "" {
-			outputFilename = strings.TrimSuffix(path.Base(inputFiles[0]), path.Ext(inputFiles[0]))
-		} else {
-			outputFilename = strings.TrimSuffix(path.Base(outputFilename), path.Ext(outputFilename))
-		}
+		outputFilename := parseInputs(args)
 
 		doConversion(inputFiles, outputFilename, &converter.Inspecter{
 			Converter: &converter.TypescriptConverter{
-				Namespace: tsNamespace,
+				Namespace:      tsNamespace,
+				Module:         tsModule,
+				Style:          tsStyle,
+				Declaration:    tsDeclaration,
+				Nullability:    tsNullability,
+				NullableSlices: tsNullableSlices,
+				Zod:            tsZod,
+				Guards:         tsGuards,
+				Int64:          tsInt64,
+				Time:           tsTime,
+				TimeParse:      tsTimeParse,
+				TimeFormat:     tsTimeFormat,
+				Duration:       tsDuration,
+				DurationParse:  tsDurationParse,
+				DurationFormat: tsDurationFormat,
+				Split:          tsSplit,
+				Brands:         tsBrands,
+				Factories:      tsFactories,
+				Constants:      tsConstants,
+				TypeIDs:        tsTypeIDs,
 			},
 			Prefix: prefix,
 			Suffix: suffix,
@@ -106,29 +192,227 @@ var cCmd = &cobra.Command{
 	Short: "Converts go structs to c",
 	Long:  `This command converts go structs to c`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(inputFiles) == 0 {
-			if len(args) == 0 {
-				fmt.Fprintln(os.Stderr, "no files specified")
-				os.Exit(1)
-			}
+		outputFilename := parseInputs(args)
+
+		guardName := cGuardName
+		if guardName == "" {
+			guardName = converter.CGuardName(outputFilename)
+		}
 
-			for i := range args {
-				inputFiles = append(inputFiles, args[i])
+		typeIDs := ""
+		if cTypeIDs {
+			typeIDs = cTypeIDsName
+			if typeIDs == "" {
+				typeIDs = converter.CIdentifier(outputFilename)
 			}
 		}
 
-		outputFilename := name
-		if outputFilename == "" {
-			outputFilename = strings.TrimSuffix(path.Base(inputFiles[0]), path.Ext(inputFiles[0]))
-		} else {
-			outputFilename = strings.TrimSuffix(path.Base(outputFilename), path.Ext(outputFilename))
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.CConverter{
+				Packed:        cPacked,
+				Pack:          cPack,
+				StaticAsserts: cStaticAsserts,
+				PointerSize:   cPointerSize,
+				BoolSize:      cBoolSize,
+				Binary:        cBinary,
+				JSON:          cJSON,
+				Lifecycle:     cLifecycle,
+				Debug:         cDebug,
+				Guard:         cGuard,
+				GuardName:     guardName,
+				ExternC:       cExternC,
+				Constants:     cConstants,
+				Source:        cSource,
+				BigEndian:     isBigEndian(),
+				CJSONInclude:  cJSONInclude,
+				TypeIDs:       typeIDs,
+				Fields:        cFields,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+			Comments: converter.Comments{
+				CIncludes: cIncludes,
+			},
+		})
+	},
+}
+
+var cBinaryCmd = &cobra.Command{
+	Use:   "c-binary",
+	Short: "Generates c pack and unpack functions for go structs",
+	Long:  `This command generates the c source for the X_pack and X_unpack functions declared by the c command with --binary`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		header := cHeader
+		if header == "" {
+			header = outputFilename + ".h"
 		}
 
 		doConversion(inputFiles, outputFilename, &converter.Inspecter{
-			Converter: &converter.CConverter{},
-			Prefix:    prefix,
-			Suffix:    suffix,
+			Converter: &converter.CBinaryConverter{
+				Header:    header,
+				BigEndian: isBigEndian(),
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+		})
+	},
+}
+
+var cJSONCmd = &cobra.Command{
+	Use:   "c-json",
+	Short: "Generates c functions converting structs to and from cJSON",
+	Long:  `This command generates the c source for the X_from_json and X_to_json functions declared by the c command with --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		header := cHeader
+		if header == "" {
+			header = outputFilename + ".h"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.CJSONConverter{
+				Header:       header,
+				CJSONInclude: cJSONInclude,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+		})
+	},
+}
+
+var cLifecycleCmd = &cobra.Command{
+	Use:   "c-lifecycle",
+	Short: "Generates c init, free, copy and equal functions for go structs",
+	Long:  `This command generates the c source for the X_init, X_free, X_copy and X_equal functions declared by the c command with --lifecycle`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		header := cHeader
+		if header == "" {
+			header = outputFilename + ".h"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.CLifecycleConverter{
+				Header: header,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+		})
+	},
+}
+
+var cDebugCmd = &cobra.Command{
+	Use:   "c-debug",
+	Short: "Generates c print and to_string functions for go structs",
+	Long:  `This command generates the c source for the X_print and X_to_string functions declared by the c command with --debug`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		header := cHeader
+		if header == "" {
+			header = outputFilename + ".h"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.CDebugConverter{
+				Header: header,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+		})
+	},
+}
+
+var goBinaryCmd = &cobra.Command{
+	Use:   "go-binary",
+	Short: "Generates go MarshalBinary and UnmarshalBinary methods",
+	Long:  `This command generates go MarshalBinary and UnmarshalBinary methods matching the c-binary encoding`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+		if name == "" {
+			// don't overwrite the input file
+			outputFilename += "_binary"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.GoBinaryConverter{
+				BigEndian: isBigEndian(),
+			},
+			Indent: indent,
+		})
+	},
+}
+
+var goCgoCmd = &cobra.Command{
+	Use:   "go-cgo",
+	Short: "Generates go functions converting structs to and from their c counterparts",
+	Long:  `This command generates the go toCX, fromCX and freeCX functions converting go structs to and from the c structs declared by the c command using cgo`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		header := cHeader
+		if header == "" {
+			header = outputFilename + ".h"
+		}
+
+		if name == "" {
+			// don't overwrite the input file
+			outputFilename += "_cgo"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.GoCgoConverter{
+				Header: header,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
+		})
+	},
+}
+
+var goTypeIDsCmd = &cobra.Command{
+	Use:   "go-type-ids",
+	Short: "Generates go type id constants for go structs",
+	Long:  `This command generates go constants and TypeID methods matching the type ids declared by the c and typescript commands with --type-ids`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+		if name == "" {
+			// don't overwrite the input file
+			outputFilename += "_type_ids"
+		}
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.GoTypeIDConverter{},
 			Indent:    indent,
+		})
+	},
+}
+
+var cppCmd = &cobra.Command{
+	Use:   "cpp",
+	Short: "Converts go structs to c++",
+	Long:  `This command converts go structs to c++17 structs using std::string, std::vector, std::map and std::optional`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outputFilename := parseInputs(args)
+
+		doConversion(inputFiles, outputFilename, &converter.Inspecter{
+			Converter: &converter.CppConverter{
+				Namespace: cppNamespace,
+				JSON:      cppJSON,
+			},
+			Prefix: prefix,
+			Suffix: suffix,
+			Indent: indent,
 			Comments: converter.Comments{
 				CIncludes: cIncludes,
 			},
@@ -147,15 +431,79 @@ func main() {
 
 	// TODO cobra won't let users specify --include "#include \"myfile.h\"" (it doesn't like the quotes)
 	cCmd.Flags().StringSliceVarP(&cIncludes, "include", "", []string{}, "include statements to add (do not include #include it will be added automatically)")
+	cCmd.Flags().BoolVarP(&cPacked, "packed", "", false, "add __attribute__((packed)) to every struct")
+	cCmd.Flags().IntVarP(&cPack, "pack", "", 0, "wrap every struct in #pragma pack(push, N)")
+	cCmd.Flags().BoolVarP(&cStaticAsserts, "static-assert", "", false, "emit static_assert checks for the computed size and member offsets of each struct")
+	cCmd.Flags().IntVarP(&cPointerSize, "pointer-size", "", 8, "the pointer size in bytes used when computing struct layouts")
+	cCmd.Flags().IntVarP(&cBoolSize, "bool-size", "", 1, "the size in bytes of bool_t used when computing struct layouts")
+	cCmd.Flags().BoolVarP(&cBinary, "binary", "", false, "declare X_pack and X_unpack functions (implemented by the c-binary command)")
+	cCmd.Flags().BoolVarP(&cJSON, "json", "", false, "declare X_from_json and X_to_json functions (implemented by the c-json command)")
+	cCmd.Flags().BoolVarP(&cLifecycle, "lifecycle", "", false, "declare X_init, X_free, X_copy and X_equal functions (implemented by the c-lifecycle command)")
+	cCmd.Flags().BoolVarP(&cDebug, "debug", "", false, "declare X_print and X_to_string functions (implemented by the c-debug command)")
+	cCmd.Flags().StringVarP(&cGuard, "guard", "", "pragma", "the include guard style (pragma or ifndef)")
+	cCmd.Flags().StringVarP(&cGuardName, "guard-name", "", "", "the macro used by the ifndef include guard (defaults to the output name, e.g. EXAMPLE_H)")
+	cCmd.Flags().BoolVarP(&cExternC, "extern-c", "", false, "wrap the declarations in extern \"C\" for c++ compilers")
+	cCmd.Flags().BoolVarP(&cSource, "source", "", false, "also generate a .c file implementing the --binary, --json, --lifecycle and --debug functions")
+	cCmd.Flags().StringVarP(&endian, "endian", "", "little", "the byte order the --source pack and unpack functions serialize with (little or big)")
+	cCmd.Flags().StringVarP(&cJSONInclude, "cjson-include", "", "\"cJSON.h\"", "how the --source file includes the cJSON header, e.g. <cjson/cJSON.h>")
+	cCmd.Flags().StringVarP(&cFields, "fields", "", "", "describe the members of every struct with an X-macro (xmacro) or a gsc_field_desc_t array (table)")
+	cCmd.Flags().BoolVarP(&cTypeIDs, "type-ids", "", false, "declare an enum of struct type ids and a table of their names and sizes")
+	cCmd.Flags().StringVarP(&cTypeIDsName, "type-ids-name", "", "", "the name of the type id enum and table (defaults to the output name, e.g. example_type_id)")
+	cCmd.Flags().BoolVarP(&cConstants, "constants", "", false, "#define the capacity of ctype arrays and the bounds of validate tags for every member")
+
+	cBinaryCmd.Flags().StringVarP(&cHeader, "header", "", "", "the generated header to include (defaults to the output name with a .h extension)")
+	cBinaryCmd.Flags().StringVarP(&endian, "endian", "", "little", "the byte order to serialize with (little or big)")
+
+	cJSONCmd.Flags().StringVarP(&cHeader, "header", "", "", "the generated header to include (defaults to the output name with a .h extension)")
+	cJSONCmd.Flags().StringVarP(&cJSONInclude, "cjson-include", "", "\"cJSON.h\"", "how to include the cJSON header, e.g. <cjson/cJSON.h>")
+
+	cLifecycleCmd.Flags().StringVarP(&cHeader, "header", "", "", "the generated header to include (defaults to the output name with a .h extension)")
+
+	cDebugCmd.Flags().StringVarP(&cHeader, "header", "", "", "the generated header to include (defaults to the output name with a .h extension)")
+
+	goCgoCmd.Flags().StringVarP(&cHeader, "header", "", "", "the generated header to include (defaults to the input name with a .h extension)")
+
+	cppCmd.Flags().StringSliceVarP(&cIncludes, "include", "", []string{}, "include statements to add (do not include #include it will be added automatically)")
+	cppCmd.Flags().StringVarP(&cppNamespace, "namespace", "", "", "the namespace to declare all structs in")
+	cppCmd.Flags().BoolVarP(&cppJSON, "json", "", false, "generate nlohmann::json to_json and from_json functions")
+
+	goBinaryCmd.Flags().StringVarP(&endian, "endian", "", "little", "the byte order to serialize with (little or big)")
 
 	typescriptCmd.Flags().StringVarP(&tsNamespace, "namespace", "", "", "the namespace to create and nest all interfaces under")
+	typescriptCmd.Flags().BoolVarP(&tsModule, "module", "", false, "export the declarations from an es module instead of declaring globals")
+	typescriptCmd.Flags().StringVarP(&tsStyle, "style", "", "interface", "declare structs as an interface, a type alias or a class with fromJSON and toJSON (interface, type, class)")
+	typescriptCmd.Flags().StringVarP(&tsNullability, "nullability", "", "", "how members can be missing or null (pointer: pointers are optional, json: pointers are null and omitempty members optional), defaults to pointer for interfaces and json for zod schemas")
+	typescriptCmd.Flags().BoolVarP(&tsGuards, "guards", "", false, "generate isX and assertX functions checking values at runtime")
+	typescriptCmd.Flags().BoolVarP(&tsZod, "zod", "", false, "generate zod schemas and the types they infer instead of interfaces")
+	typescriptCmd.Flags().BoolVarP(&tsNullableSlices, "nullable-slices", "", false, "slices and maps can be null, as encoding/json encodes nil ones")
+	typescriptCmd.Flags().StringVarP(&tsInt64, "int64", "", "number", "the typescript type of int64 and uint64 (number, bigint, or string for members with the ,string json option)")
+	typescriptCmd.Flags().StringVarP(&tsTime, "time", "", "", "the typescript type of time.Time (string, Date, ISODateString or a user type such as moment.Moment), defaults to Date for classes and string otherwise")
+	typescriptCmd.Flags().StringVarP(&tsTimeParse, "time-parse", "", "", "the function turning an RFC 3339 string into the user time type, e.g. moment")
+	typescriptCmd.Flags().StringVarP(&tsTimeFormat, "time-format", "", "", "the function turning the user time type into an RFC 3339 string, defaults to its toJSON method")
+	typescriptCmd.Flags().StringVarP(&tsDuration, "duration", "", "number", "the typescript type of time.Duration (number of nanoseconds or a user type such as moment.Duration)")
+	typescriptCmd.Flags().StringVarP(&tsDurationParse, "duration-parse", "", "", "the function turning nanoseconds into the user duration type")
+	typescriptCmd.Flags().StringVarP(&tsDurationFormat, "duration-format", "", "", "the function turning the user duration type into nanoseconds")
+	typescriptCmd.Flags().StringVarP(&tsSplit, "split", "", "", "generate an es module per go package or input file and an index module re-exporting them (package, file)")
+	typescriptCmd.Flags().BoolVarP(&tsBrands, "brands", "", false, "declare named scalar types such as type UserID int64 as branded types with a constructor function")
+	typescriptCmd.Flags().BoolVarP(&tsFactories, "factories", "", false, "generate a module of makeX(overrides) test data factories next to the types")
+	typescriptCmd.Flags().BoolVarP(&tsDeclaration, "declaration", "", false, "generate a declaration only .d.ts file")
 	typescriptCmd.Flags().StringSliceVarP(&tsImports, "import", "", []string{}, "import statements to add")
+	typescriptCmd.Flags().BoolVarP(&tsTypeIDs, "type-ids", "", false, "declare consts for the struct type ids")
+	typescriptCmd.Flags().BoolVarP(&tsConstants, "constants", "", false, "declare consts for the capacity of ctype arrays and the bounds of validate tags for every member")
 
 	// TODO if we need to add require statements, it will be messy dealing with cleaning the string `const { mything, anotherthing } = require('lodash');`
 	// typescriptCmd.Flags().StringSliceVarP(&tsRequires, "require", "", []string{}, "require statements to add")
 
 	rootCmd.AddCommand(typescriptCmd)
 	rootCmd.AddCommand(cCmd)
+	rootCmd.AddCommand(cBinaryCmd)
+	rootCmd.AddCommand(cJSONCmd)
+	rootCmd.AddCommand(cLifecycleCmd)
+	rootCmd.AddCommand(cDebugCmd)
+	rootCmd.AddCommand(cppCmd)
+	rootCmd.AddCommand(goBinaryCmd)
+	rootCmd.AddCommand(goCgoCmd)
+	rootCmd.AddCommand(goTypeIDsCmd)
 
 	rootCmd.Execute()
 }